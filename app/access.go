@@ -0,0 +1,56 @@
+package app
+
+import (
+	"encoding/json"
+
+	"github.com/go-msvc/errors"
+)
+
+// ItemAccess tells who may enter an item, from "auth" and "roles" in the app file
+// the web server checks it before on_enter_actions and Render()
+type ItemAccess struct {
+	AuthRequired bool
+	Roles        []string //user needs one of these roles, implies AuthRequired
+}
+
+// Check returns KindAuthRequired when the item needs a user and there is none,
+// or KindForbidden when the user does not have one of the roles
+func (access ItemAccess) Check(user *User) error {
+	if !access.AuthRequired && len(access.Roles) == 0 {
+		return nil
+	}
+	if user == nil {
+		return NewError(KindAuthRequired, "please log in to continue")
+	}
+	if len(access.Roles) == 0 {
+		return nil
+	}
+	for _, role := range access.Roles {
+		if user.HasRole(role) {
+			return nil
+		}
+	}
+	return NewError(KindForbidden, "user(%s) does not have any of roles %v", user.ID, access.Roles)
+} //ItemAccess.Check()
+
+// itemAuth is the "auth" attribute of an item
+type itemAuth string
+
+const (
+	itemAuthNone     itemAuth = "none"     //default, anyone may enter
+	itemAuthRequired itemAuth = "required" //only authenticated users
+)
+
+func (a *itemAuth) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	switch itemAuth(s) {
+	case itemAuthNone, itemAuthRequired:
+		*a = itemAuth(s)
+	default:
+		return errors.Errorf("invalid auth \"%s\" (expect %s|%s)", s, itemAuthNone, itemAuthRequired)
+	}
+	return nil
+}
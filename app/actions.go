@@ -3,6 +3,7 @@ package app
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"reflect"
 	"strings"
 
@@ -25,6 +26,7 @@ func (actions *Actions) Validate(app App) error {
 }
 
 func (actions Actions) Execute(ctx context.Context) (err error) {
+	log := LoggerFrom(ctx)
 	log.Debugf("Executing %d actions ...", len(actions.list))
 	for actionIndex, action := range actions.list {
 		log.Debugf("Executing action[%d]:(%T) ...", actionIndex, action)
@@ -90,6 +92,31 @@ func (actions *Actions) UnmarshalJSON(value []byte) error {
 	return nil
 }
 
+// MarshalJSON writes the actions in the same shape as parsed by UnmarshalJSON
+func (actions Actions) MarshalJSON() ([]byte, error) {
+	actionList := []map[string]interface{}{}
+	for _, action := range actions.list {
+		switch a := action.(type) {
+		case *actionFunc:
+			actionList = append(actionList, map[string]interface{}{a.set: map[string]interface{}{a.name + "()": a.req}})
+		case *actionSet:
+			actionList = append(actionList, map[string]interface{}{a.set: a.value})
+		}
+	}
+	return json.Marshal(actionList)
+}
+
+// funcNames returns the names of funcs called by the actions
+func (actions Actions) funcNames() []string {
+	names := []string{}
+	for _, action := range actions.list {
+		if f, ok := action.(*actionFunc); ok {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
+
 type Action interface {
 	Validate(app App) error
 	Execute(ctx context.Context) error //todo...add req...
@@ -117,10 +144,17 @@ func (f *actionFunc) Validate(app App) error {
 	return nil
 }
 
-func (f actionFunc) Execute(ctx context.Context) error {
-	args := []reflect.Value{
-		reflect.ValueOf(ctx),
+func (f actionFunc) String() string {
+	jsonReq, _ := json.Marshal(f.req)
+	if f.set == "" {
+		return fmt.Sprintf("%s(%s)", f.name, jsonReq)
 	}
+	return fmt.Sprintf("%s = %s(%s)", f.set, f.name, jsonReq)
+}
+
+func (f actionFunc) Execute(ctx context.Context) error {
+	log := LoggerFrom(ctx)
+	args := []reflect.Value{}
 	if f.fnc.reqType != nil {
 		//todo: execute req templates into a value... for now just static value as configured
 		log.Debugf("f.req: (%T)%+v", f.req, f.req)
@@ -138,12 +172,15 @@ func (f actionFunc) Execute(ctx context.Context) error {
 		log.Debugf("req: (%T)%+v", reqValuePtr.Elem().Interface(), reqValuePtr.Elem().Interface())
 		args = append(args, reqValuePtr.Elem())
 	}
-	results := f.fnc.funcValue.Call(args)
+	results, err := f.fnc.call(ctx, args...)
+	if err != nil {
+		return errors.Wrapf(err, "cannot call %s()", f.name)
+	}
 	errValue := results[len(results)-1] //i.e. last result from the func
 	log.Debugf("err valid: %v", errValue.IsValid())
 	log.Debugf("err nil: %v", errValue.IsNil())
 	if !errValue.IsNil() {
-		return errors.Wrapf(errValue.Interface().(error), "action func %s() failed", f.name)
+		return WrapError(KindUpstream, errValue.Interface().(error), "action func %s() failed", f.name)
 	}
 
 	if f.set != "" {
@@ -152,7 +189,7 @@ func (f actionFunc) Execute(ctx context.Context) error {
 		}
 		session := ctx.Value(CtxSession{}).(*sessions.Session)
 		session.Values[f.set] = results[0].Interface()
-		log.Debugf("action %s(): %s = (%T)%+v", f.name, f.set, results[0].Interface(), results[0].Interface())
+		log.Debugf("action %s(): %s = (%T)%+v", f.name, f.set, results[0].Interface(), logValue(ctx, f.set, results[0].Interface()))
 	}
 	return nil
 }
@@ -169,10 +206,15 @@ func (f *actionSet) Validate(app App) error {
 	return nil
 }
 
+func (f actionSet) String() string {
+	jsonValue, _ := json.Marshal(f.value)
+	return fmt.Sprintf("%s = %s", f.set, jsonValue)
+}
+
 func (f actionSet) Execute(ctx context.Context) error {
 	session := ctx.Value(CtxSession{}).(*sessions.Session)
 	session.Values[f.set] = f.value
-	log.Debugf("action set \"%s\" = (%T)%+v", f.set, f.value, f.value)
+	LoggerFrom(ctx).Debugf("action set \"%s\" = (%T)%+v", f.set, f.value, logValue(ctx, f.set, f.value))
 	return nil
 }
 
@@ -3,29 +3,46 @@ package app
 import (
 	"context"
 	"encoding/gob"
-	"encoding/json"
 	"fmt"
-	"os"
+	"io"
 	"reflect"
 	"regexp"
+	"sync"
+	"time"
 
 	"github.com/go-msvc/errors"
-	"github.com/go-msvc/logger"
 )
 
-var log = logger.New().WithLevel(logger.LevelDebug)
-
 type App interface {
 	//RegisterFunc:
 	//	appFunc must be a func taking args (context.Context, optional request)
 	//			and respond with (optional response, error)
 	RegisterFunc(name string, appFunc interface{}) error
 	FuncByName(name string) (*AppFunc, bool)
-	Load(filename string) error
-	GetItem(id string) (AppItem, bool)
+	Load(patterns ...string) error
+	LoadVersion(version string, patterns ...string) error
+	GetItem(version string, id string) (AppItem, bool)
+
+	//Version is the current version of the app, new sessions must use it
+	//Versions are all loaded versions, including those still used by older sessions
+	Version() string
+	Versions() []string
+
+	//Reload the loaded files, and Watch them to reload when changed
+	Reload() error
+	Watch(ctx context.Context, interval time.Duration) error
+
+	//RenderPage executes the named template (e.g. "menu") inside the page template
+	RenderPage(buffer io.Writer, templateName string, data TmplData) error
+
+	//ItemIds and ItemInfo describe the loaded items, e.g. for tools
+	ItemIds() []string
+	ItemInfo(id string) (ItemInfo, bool)
 }
 
 type AppFunc struct {
+	name      string
+	takesUser bool //*User after ctx, from UserFrom(ctx)
 	reqType   reflect.Type
 	resType   reflect.Type
 	funcValue reflect.Value
@@ -34,22 +51,68 @@ type AppFunc struct {
 func init() {
 	//register types stored in session data, else session save will fail
 	gob.Register(PageData{})
+	gob.Register(User{})
 	gob.Register(map[string]interface{}{})
 	gob.Register(ColumnList{})
 	gob.Register(ColumnItem{})
 	gob.Register(map[string]ColumnItem{})
 }
 
-func New() App {
-	return &app{
-		funcs: map[string]*AppFunc{},
-		items: map[string]AppItem{},
+// Option configures the app in New()
+type Option func(app *app)
+
+// WithUnknownAttributes selects what Load does with JSON attributes
+// that are not used by the app, default is UnknownAttributesFail
+func WithUnknownAttributes(mode UnknownAttributes) Option {
+	return func(app *app) {
+		app.unknownAttributes = mode
+	}
+}
+
+// WithDanglingRefs selects what Load does with references to unknown items,
+// default is DanglingRefsFail
+func WithDanglingRefs(mode DanglingRefs) Option {
+	return func(app *app) {
+		app.danglingRefs = mode
 	}
 }
 
+// WithRetireAfter sets how long an older version of the app remains available
+// for sessions that started on it, after a new version was loaded
+// default is 1 hour, 0 keeps older versions until the server restarts
+func WithRetireAfter(d time.Duration) Option {
+	return func(app *app) {
+		app.retireAfter = d
+	}
+}
+
+func New(options ...Option) App {
+	app := &app{
+		funcs:             map[string]*AppFunc{},
+		current:           &definition{},
+		versions:          map[string]*definition{},
+		retireAfter:       time.Hour,
+		unknownAttributes: UnknownAttributesFail,
+		danglingRefs:      DanglingRefsFail,
+	}
+	for _, option := range options {
+		option(app)
+	}
+	return app
+}
+
 type app struct {
 	funcs map[string]*AppFunc
-	items map[string]AppItem
+
+	loading     sync.Mutex   //serialise Load() and Reload()
+	mutex       sync.RWMutex //protects current and versions which change on (re)load
+	current     *definition
+	versions    map[string]*definition //current and retiring versions
+	retireAfter time.Duration
+
+	unknownAttributes UnknownAttributes
+	danglingRefs      DanglingRefs
+	templates         templates
 }
 
 func (app *app) MustRegisterFunc(name string, appFunc interface{}) {
@@ -60,7 +123,16 @@ func (app *app) MustRegisterFunc(name string, appFunc interface{}) {
 
 var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
 var errorType = reflect.TypeOf((*error)(nil)).Elem()
+var userType = reflect.TypeOf((*User)(nil))
 
+// RegisterFunc makes a func available to items in app files by name
+// the func is one of:
+//
+//	func(ctx context.Context[, user *app.User][, req <type>]) [(<res type>, ] error)
+//
+// only a *app.User after ctx is the user, so a func may take app.User as req
+// funcs that take the user are called with a copy of the authenticated user (see UserFrom()),
+// and fail with KindAuthRequired when there is none
 func (app *app) RegisterFunc(name string, appFunc interface{}) error {
 	if _, ok := app.funcs[name]; ok {
 		return errors.Errorf("register func %s() already registered", name)
@@ -72,8 +144,9 @@ func (app *app) RegisterFunc(name string, appFunc interface{}) error {
 	if funcType.NumIn() < 1 || funcType.In(0) != contextType {
 		return errors.Errorf("%s() first arg %v is not %v", name, funcType.In(0), contextType)
 	}
-	if funcType.NumIn() > 2 {
-		return errors.Errorf("%s() takes more args than only (ctx, req)", name)
+	takesUser := funcType.NumIn() > 1 && funcType.In(1) == userType
+	if funcType.NumIn() > 3 || (funcType.NumIn() > 2 && !takesUser) {
+		return errors.Errorf("%s() takes more args than only (ctx, [user,] req)", name)
 	}
 	if funcType.NumOut() < 1 || funcType.Out(funcType.NumOut()-1) != errorType {
 		return errors.Errorf("%s() last result %v is not %v", name, funcType.Out(funcType.NumOut()-1), errorType)
@@ -83,10 +156,16 @@ func (app *app) RegisterFunc(name string, appFunc interface{}) error {
 	}
 
 	info := &AppFunc{
+		name:      name,
+		takesUser: takesUser,
 		funcValue: reflect.ValueOf(appFunc),
 	}
-	if funcType.NumIn() == 2 {
-		info.reqType = funcType.In(1)
+	reqIndex := 1
+	if takesUser {
+		reqIndex = 2
+	}
+	if funcType.NumIn() > reqIndex {
+		info.reqType = funcType.In(reqIndex)
 	}
 	if funcType.NumOut() == 2 {
 		info.resType = funcType.Out(0)
@@ -96,7 +175,7 @@ func (app *app) RegisterFunc(name string, appFunc interface{}) error {
 	return nil
 } //app.RegisterFunc()
 
-func (app app) FuncByName(name string) (*AppFunc, bool) {
+func (app *app) FuncByName(name string) (*AppFunc, bool) {
 	fnc, ok := app.funcs[name]
 	if ok {
 		return fnc, true
@@ -104,32 +183,75 @@ func (app app) FuncByName(name string) (*AppFunc, bool) {
 	return nil, false
 } //app.FuncByName()
 
-func (app *app) Load(filename string) error {
-	f, err := os.Open(filename)
+// Load items from JSON files and merge them into the app
+// each pattern may be a file name, a directory or a glob pattern
+// all files (also those loaded before) are read and validated before the
+// items are replaced, so it either loads all or nothing
+// the loaded items become the current version identified by a hash of the files
+func (app *app) Load(patterns ...string) error {
+	app.loading.Lock()
+	defer app.loading.Unlock()
+	allPatterns := append(append([]string{}, app.definition().patterns...), patterns...)
+	def, err := app.loadDefinition("", allPatterns)
 	if err != nil {
-		return errors.Wrapf(err, "failed to open file %s", filename)
+		return err
 	}
-	defer f.Close()
+	app.setDefinition(def)
+	return nil
+} //app.Load()
 
-	fileItems := map[string]item{}
-	if err := json.NewDecoder(f).Decode(&fileItems); err != nil {
-		return errors.Wrapf(err, "failed to read items from JSON file %s", filename)
+// LoadVersion loads a new named version of the app from only the specified files
+// and makes it the current version, previous versions remain in use by existing
+// sessions until they are retired (see WithRetireAfter())
+func (app *app) LoadVersion(version string, patterns ...string) error {
+	if version == "" {
+		return errors.Errorf("missing version")
 	}
-	for id, item := range fileItems {
-		if !itemIdRegex.MatchString(id) {
-			return errors.Errorf("missing/invalid item id \"%s\" (expect lower alnum with dashes, e.g. \"my-item1-loader\")", id)
-		}
-		if err := item.Validate(app); err != nil {
-			return errors.Wrapf(err, "invalid item \"%s\"", id)
-		}
-		//todo: validate etc...
-		app.items[id] = item
+	app.loading.Lock()
+	defer app.loading.Unlock()
+	def, err := app.loadDefinition(version, patterns)
+	if err != nil {
+		return err
 	}
+	app.setDefinition(def)
 	return nil
-}
+} //app.LoadVersion()
+
+// Reload reads all the files of the current version and if anything changed
+// makes it the new current version, only when the new definition is valid,
+// else the current version remains in use
+// a version named with LoadVersion() keeps its name, so sessions on it use the
+// reloaded items, else the new version is named by its hash
+func (app *app) Reload() error {
+	app.loading.Lock()
+	defer app.loading.Unlock()
+	current := app.definition()
+	if len(current.patterns) == 0 {
+		return errors.Errorf("nothing loaded to reload")
+	}
+	version := ""
+	if current.named {
+		version = current.version
+	}
+	def, err := app.loadDefinition(version, current.patterns)
+	if err != nil {
+		return err
+	}
+	if def.hash == current.hash {
+		log.Debugf("Reload: version %s did not change", current.version)
+		return nil
+	}
+	app.setDefinition(def)
+	return nil
+} //app.Reload()
 
-func (app *app) GetItem(id string) (AppItem, bool) {
-	item, ok := app.items[id]
+// GetItem returns the item from the specified version, or the current version if ""
+func (app *app) GetItem(version string, id string) (AppItem, bool) {
+	def, ok := app.versionDefinition(version)
+	if !ok {
+		return nil, false
+	}
+	item, ok := def.items[id]
 	if !ok {
 		return nil, false
 	}
@@ -139,7 +261,11 @@ func (app *app) GetItem(id string) (AppItem, bool) {
 type CtxSession struct{}
 type CtxPageData struct{}
 
+// PageData is kept in the session for the last rendered page
+// Token and Item are set by the web server, the item sets the rest in Render()
 type PageData struct {
+	Token string                  //page token of the render, forms post it to be processed (see CtxPageToken)
+	Item  string                  //id of the item that rendered the page
 	Links map[string]fileItemNext //key is uuid for mapping URL ?next=<uuid> -> next steps
 	Data  interface{}             //anything else the page needs in Process()
 }
@@ -151,3 +277,6 @@ type PageData struct {
 const itemIdPattern = `[a-z]([a-z0-9-]*[a-z0-9])*`
 
 var itemIdRegex = regexp.MustCompile("^" + itemIdPattern + "$")
+
+// item ids loaded from sub directories are prefixed with the namespace, e.g. "profile/edit"
+var namespacedItemIdRegex = regexp.MustCompile("^" + itemIdPattern + "(/" + itemIdPattern + ")*$")
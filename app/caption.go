@@ -2,6 +2,7 @@ package app
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"html/template"
 	"strings"
@@ -46,6 +47,10 @@ func (lc *ConfiguredTemplate) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+func (ct ConfiguredTemplate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ct.UnparsedTemplate)
+}
+
 func (ct ConfiguredTemplate) Validate() error {
 	if ct.tmpl == nil {
 		return errors.Errorf("missing template")
@@ -3,12 +3,11 @@ package app
 import (
 	"context"
 	"encoding/gob"
-	"encoding/json"
 	"fmt"
-	"html/template"
 	"io"
 	"net/http"
 	"reflect"
+	"strings"
 
 	"github.com/go-msvc/data"
 	"github.com/go-msvc/errors"
@@ -33,70 +32,84 @@ func (edit *edit) Validate(app App) error {
 	if edit.getFunc, ok = app.FuncByName(edit.GetFuncName); !ok {
 		return errors.Errorf("missing/unknown get_func:\"%s\"", edit.GetFuncName)
 	}
+	if edit.GetArgName != "" && edit.getFunc.reqType == nil {
+		return errors.Errorf("get_func:\"%s\" does not take an argument for get_arg_name:\"%s\"", edit.GetFuncName, edit.GetArgName)
+	}
+	if edit.GetArgName == "" && edit.getFunc.reqType != nil {
+		return errors.Errorf("get_func:\"%s\" requires get_arg_name", edit.GetFuncName)
+	}
+	if edit.getFunc.resType == nil {
+		return errors.Errorf("get_func:\"%s\" does not return an item", edit.GetFuncName)
+	}
 	if edit.updFunc, ok = app.FuncByName(edit.UpdFuncName); !ok {
 		return errors.Errorf("missing/unknown upd_func:\"%s\"", edit.UpdFuncName)
 	}
+	if edit.updFunc.reqType == nil {
+		return errors.Errorf("upd_func:\"%s\" does not take the item as argument", edit.UpdFuncName)
+	}
 	if err := edit.SavedNext.Validate(); err != nil {
 		return errors.Wrapf(err, "invalid saved_next")
 	}
 	return nil
 } //edit.Validate()
 
-func (edit edit) Render(ctx context.Context, buffer io.Writer) (*PageData, error) {
+func (edit edit) Render(ctx context.Context, buffer io.Writer) (string, *PageData, error) {
+	log := LoggerFrom(ctx)
 	lang := ctx.Value(CtxLang{}).(string)
 	session := ctx.Value(CtxSession{}).(*sessions.Session)
 
-	//clear item and then call actions to fetch item
-	delete(session.Values, "Item")
 	//call get function
-	args := []reflect.Value{
-		reflect.ValueOf(ctx),
-	}
+	args := []reflect.Value{}
 	if edit.GetArgName != "" {
 		req, ok := session.Values[edit.GetArgName]
 		if !ok {
 			var err error
 			req, err = data.Get(sessionData(session), edit.GetArgName)
 			if err != nil {
-				return nil, errors.Wrapf(err, "get_func(req:%s) not defined", edit.GetArgName)
+				return "", nil, errors.Wrapf(err, "get_func(req:%s) not defined", edit.GetArgName)
 			}
 		}
 		args = append(args, reflect.ValueOf(req))
 	}
-	results := edit.getFunc.funcValue.Call(args)
+	results, err := edit.getFunc.call(ctx, args...)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "cannot get item")
+	}
 	errValue := results[len(results)-1]
 	if !errValue.IsNil() {
-		return nil, errors.Wrapf(errValue.Interface().(error), "failed to get item")
+		return "", nil, WrapError(KindUpstream, errValue.Interface().(error), "failed to get item")
 	}
 	if len(results) != 2 {
-		return nil, errors.Errorf("get_func(%s) does not return a value", edit.GetFuncName)
+		return "", nil, errors.Errorf("get_func(%s) does not return a value", edit.GetFuncName)
 	}
 	item := results[0].Interface()
 	//todo: need way to register custom types else they cannot be stored in profile
 	//this might be expensive... not sure
 	gob.Register(item)
-	session.Values["Item"] = item //used by Process() to get type
 
 	log.Debugf("Editor for %T", item)
 	structType := reflect.TypeOf(item)
 	if structType.Kind() != reflect.Struct {
-		return nil, errors.Errorf("edit.get_actions returned %T which is not a struct", item)
+		return "", nil, errors.Errorf("edit.get_actions returned %T which is not a struct", item)
 	}
 	structValue := reflect.ValueOf(item)
 
 	//start prepare the template data so we can add info
 	//about fields
+	//the item is kept with the page, so Process() applies the form to the item
+	//of this page and not of another page rendered since
 	pageData := PageData{
 		Links: map[string]fileItemNext{},
-		Data:  nil,
+		Data:  item,
 	}
 	title, err := edit.Title.Render(lang, sessionData(session))
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to render title")
+		return "", nil, errors.Wrapf(err, "failed to render title")
 	}
 	editTmplData := tmplDataForEdit{
-		Title:  title,
-		Fields: []tmplDataForEditField{},
+		Title:      title,
+		Fields:     []tmplDataForEditField{},
+		FormTokens: formTokens(ctx),
 	}
 	for i := 0; i < structType.NumField(); i++ {
 		f := structType.Field(i)
@@ -109,32 +122,35 @@ func (edit edit) Render(ctx context.Context, buffer io.Writer) (*PageData, error
 			Name:  f.Name,
 			Value: fmt.Sprintf("%v", structValue.Field(i).Interface()),
 		}
+		if posted, ok := formValue(ctx, f.Name); ok {
+			fieldData.Value = posted //input that was not accepted
+		}
 		editTmplData.Fields = append(editTmplData.Fields, fieldData)
 	}
 
-	{
-		log.Debugf("editTmplData: %+v", editTmplData)
-		j, _ := json.Marshal(editTmplData)
-		log.Debugf("editTmplData: %s", string(j))
-	}
-
-	tmplData := TmplData{
-		NavBar: TmplNavBar{
-			Email: "a@b.c", //todo...
-		},
-		Body: editTmplData,
+	tmplData := PageTmplData(ctx, editTmplData)
+	if err := renderPage(ctx, buffer, "edit", tmplData); err != nil {
+		return "", nil, errors.Wrapf(err, "failed to exec edit template")
 	}
-	if err := editTmpl.ExecuteTemplate(buffer, "page", tmplData); err != nil {
-		return nil, errors.Wrapf(err, "failed to exec edit template")
-	}
-	return &pageData, nil
+	return "", &pageData, nil
 } //edit.Render()
 
+func (edit edit) nextLists() []itemNextList {
+	return []itemNextList{{path: "saved_next", next: edit.SavedNext}}
+}
+
+func (edit edit) funcNames() []string {
+	return []string{edit.GetFuncName, edit.UpdFuncName}
+}
+
 func (edit edit) Process(ctx context.Context, httpReq *http.Request) (string, error) {
+	log := LoggerFrom(ctx)
 	httpReq.ParseForm()
-	log.Debugf("form data: %+v", httpReq.Form)
-	session := ctx.Value(CtxSession{}).(*sessions.Session)
-	item := session.Values["Item"] //consider making this uuid so that re-submit of old form has no effect
+	pageData, _ := ctx.Value(CtxPageData{}).(PageData)
+	item := pageData.Data
+	if item == nil {
+		return "", errors.Errorf("edit page has no item")
+	}
 
 	//apply the form values to struct fields
 	structType := reflect.TypeOf(item)
@@ -143,29 +159,41 @@ func (edit edit) Process(ctx context.Context, httpReq *http.Request) (string, er
 	}
 
 	//make a new copy of item which we can edit
+	//fields not in the form keep their value, an empty value clears the field
+	//(sets the zero value) and upd_func must check for required fields
 	newValuePtr := reflect.New(structType)
+	newValuePtr.Elem().Set(reflect.ValueOf(item))
 	for i := 0; i < structType.NumField(); i++ {
 		f := structType.Field(i)
+		if _, ok := httpReq.Form[f.Name]; !ok || !f.IsExported() {
+			continue
+		}
 		v := httpReq.Form.Get(f.Name)
-		if n, err := fmt.Sscanf(v, "%v", newValuePtr.Elem().Field(i).Addr().Interface()); err != nil || n != 1 {
-			return "", errors.Wrapf(err, "failed to parse \"%s\" into %T", v, newValuePtr.Elem().Field(i).Interface())
+		field := newValuePtr.Elem().Field(i)
+		switch {
+		case field.Kind() == reflect.String:
+			field.SetString(v) //as is, Sscanf would stop at the first space
+		case strings.TrimSpace(v) == "":
+			field.Set(reflect.Zero(f.Type))
+		default:
+			if n, err := fmt.Sscanf(strings.TrimSpace(v), "%v", field.Addr().Interface()); err != nil || n != 1 {
+				return "", WrapError(KindValidation, err, "invalid %s \"%s\"", f.Name, v)
+			}
 		}
-		x := newValuePtr.Elem().Field(i).Interface()
-		log.Debugf("%s: \"%s\" -> (%T)%+v", f.Name, v, x, x)
+		x := field.Interface()
+		log.Debugf("%s: \"%s\" -> (%T)%+v", f.Name, logValue(ctx, f.Name, v), x, logValue(ctx, f.Name, x))
 	}
 	item = newValuePtr.Elem().Interface()
-	log.Debugf("Edited Item: (%T)%+v", item, item)
 
 	//call update function
-	results := edit.updFunc.funcValue.Call([]reflect.Value{
-		reflect.ValueOf(ctx),
-		reflect.ValueOf(item), //updated item
-	})
+	results, err := edit.updFunc.call(ctx, reflect.ValueOf(item)) //updated item
+	if err != nil {
+		return "", errors.Wrapf(err, "cannot update item")
+	}
 	errValue := results[len(results)-1]
 	if !errValue.IsNil() {
-		return "", errors.Wrapf(errValue.Interface().(error), "failed to update item")
+		return "", WrapError(KindUpstream, errValue.Interface().(error), "failed to update item")
 	}
-	session.Values["Item"] = item
 	nextItemId, err := edit.SavedNext.Execute(ctx)
 	if err != nil {
 		return "", errors.Errorf("failed to get next")
@@ -174,8 +202,9 @@ func (edit edit) Process(ctx context.Context, httpReq *http.Request) (string, er
 } //edit.Process()
 
 type tmplDataForEdit struct {
-	Title  string
-	Fields []tmplDataForEditField
+	Title      string
+	Fields     []tmplDataForEditField
+	FormTokens FormTokens
 }
 
 type tmplDataForEditField struct {
@@ -183,13 +212,3 @@ type tmplDataForEditField struct {
 	Name  string //name of value in struct
 	Value string //value to put in form
 }
-
-var editTmpl *template.Template
-
-func init() {
-	var err error
-	editTmpl, err = LoadPageTemplates([]string{"edit"})
-	if err != nil {
-		panic(fmt.Sprintf("failed to load edit template: %+v", err))
-	}
-} //init()
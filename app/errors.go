@@ -0,0 +1,118 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/go-msvc/errors"
+)
+
+// ErrorKind classifies errors from Render(), Process() and navigation,
+// so that the web server can respond with the right status and error page
+type ErrorKind int
+
+const (
+	KindInternal       ErrorKind = iota //default for errors without a kind
+	KindNotFound                        //unknown item, e.g. a link from an old page
+	KindValidation                      //invalid user input
+	KindUpstream                        //a registered app func failed
+	KindSessionExpired                  //session expired or its app version was retired
+	KindAuthRequired                    //item requires an authenticated user
+	KindForbidden                       //user does not have a role required by the item
+)
+
+func (kind ErrorKind) String() string {
+	switch kind {
+	case KindInternal:
+		return "internal"
+	case KindNotFound:
+		return "not_found"
+	case KindValidation:
+		return "validation"
+	case KindUpstream:
+		return "upstream"
+	case KindSessionExpired:
+		return "session_expired"
+	case KindAuthRequired:
+		return "auth_required"
+	case KindForbidden:
+		return "forbidden"
+	}
+	return fmt.Sprintf("kind(%d)", int(kind))
+}
+
+// Error is an error with a kind, it can be wrapped with errors.Wrapf() like any other error
+type Error struct {
+	Kind   ErrorKind
+	parent error
+	caller errors.Caller
+	msg    string
+}
+
+// NewError returns an error of the kind, e.g. NewError(KindValidation, "invalid national id")
+// the message is shown to users for KindValidation, so write it for them
+func NewError(kind ErrorKind, format string, args ...interface{}) error {
+	return Error{
+		Kind:   kind,
+		caller: errors.GetCaller(2),
+		msg:    fmt.Sprintf(format, args...),
+	}
+}
+
+// WrapError returns an error of the kind caused by err
+func WrapError(kind ErrorKind, err error, format string, args ...interface{}) error {
+	return Error{
+		Kind:   kind,
+		parent: err,
+		caller: errors.GetCaller(2),
+		msg:    fmt.Sprintf(format, args...),
+	}
+}
+
+func (e Error) Parent() error         { return e.parent }
+func (e Error) Caller() errors.Caller { return e.caller }
+func (e Error) Message() string       { return e.msg }
+
+func (e Error) Error() string {
+	if e.parent == nil {
+		return e.msg
+	}
+	return e.msg + " because " + e.parent.Error()
+}
+
+// KindOf returns the kind of the error closest to the cause in the chain of parents,
+// e.g. a func that returned KindValidation wrapped in KindUpstream is KindValidation
+// errors without a kind are KindInternal
+func KindOf(err error) ErrorKind {
+	kindErr, ok := kindError(err)
+	if !ok {
+		return KindInternal
+	}
+	return kindErr.Kind
+}
+
+// ErrorMessage is the message of the error with the kind of KindOf(), e.g. for users
+// to see what to correct after KindValidation, or "" without a kind
+func ErrorMessage(err error) string {
+	kindErr, ok := kindError(err)
+	if !ok {
+		return ""
+	}
+	return kindErr.msg
+}
+
+func kindError(err error) (Error, bool) {
+	found := Error{}
+	ok := false
+	for err != nil {
+		if e, isKindErr := err.(Error); isKindErr {
+			found = e
+			ok = true
+		}
+		parentErr, hasParent := err.(errors.IError)
+		if !hasParent {
+			break
+		}
+		err = parentErr.Parent()
+	}
+	return found, ok
+}
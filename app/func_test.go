@@ -0,0 +1,31 @@
+package app
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestFuncUserCopy(t *testing.T) {
+	a := New()
+	if err := a.RegisterFunc("promote", func(ctx context.Context, user *User) error {
+		user.Name = "changed"
+		user.Roles[0] = "admin"
+		user.Roles = append(user.Roles, "owner")
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register: %+v", err)
+	}
+	fnc, _ := a.FuncByName("promote")
+	if _, err := fnc.call(context.Background()); KindOf(err) != KindAuthRequired {
+		t.Fatalf("called without user: %+v", err)
+	}
+
+	user := &User{ID: "1", Name: "Test", Roles: []string{"user", "viewer"}}
+	if _, err := fnc.call(context.WithValue(context.Background(), CtxUser{}, user)); err != nil {
+		t.Fatalf("failed to call: %+v", err)
+	}
+	if user.Name != "Test" || !reflect.DeepEqual(user.Roles, []string{"user", "viewer"}) {
+		t.Fatalf("func changed the session user to %+v", *user)
+	}
+}
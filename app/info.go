@@ -0,0 +1,61 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ItemInfo describes a loaded item, e.g. for tools that document the app
+type ItemInfo struct {
+	Id         string
+	Type       string          //item type, e.g. "menu"
+	Source     string          //file:line where the item was defined
+	Access     ItemAccess      //who may enter the item
+	Actions    []string        //on_enter_actions, e.g. "Items = listOfJobs({})"
+	Funcs      []string        //names of funcs called by the item
+	Refs       []ItemRef       //references to next items
+	Definition json.RawMessage //the item as loaded, with item ids resolved
+}
+
+func (app *app) ItemIds() []string {
+	return sortedItemIds(app.definition().items)
+} //app.ItemIds()
+
+func (app *app) ItemInfo(id string) (ItemInfo, bool) {
+	def := app.definition()
+	i, ok := def.items[id].(item)
+	if !ok {
+		return ItemInfo{}, false
+	}
+	info := ItemInfo{
+		Id:      id,
+		Type:    i.kind,
+		Source:  def.sources[id].String(),
+		Access:  i.Access(),
+		Actions: []string{},
+		Funcs:   i.funcNames(),
+		Refs:    i.refs(),
+	}
+	if i.OnEnter != nil {
+		for _, action := range i.OnEnter.list {
+			info.Actions = append(info.Actions, fmt.Sprintf("%v", action))
+		}
+	}
+	var err error
+	if info.Definition, err = json.Marshal(i); err != nil {
+		log.Errorf("failed to encode item(%s): %+v", id, err)
+	}
+	return info, true
+} //app.ItemInfo()
+
+// funcNames returns the names of all funcs called by the item
+func (i item) funcNames() []string {
+	names := []string{}
+	if i.OnEnter != nil {
+		names = append(names, i.OnEnter.funcNames()...)
+	}
+	if body, ok := i.body.(interface{ funcNames() []string }); ok {
+		names = append(names, body.funcNames()...)
+	}
+	return names
+} //item.funcNames()
@@ -2,14 +2,17 @@ package app
 
 import (
 	"context"
+	"encoding/json"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/go-msvc/errors"
 )
 
 type AppItem interface {
 	OnEnterActions() *Actions
+	Access() ItemAccess
 	Render(ctx context.Context, buffer io.Writer) (
 		nextItemId string, //only for redirect
 		pageData *PageData, //only when ready to display
@@ -21,64 +24,146 @@ type AppItem interface {
 	Process(ctx context.Context, httpReq *http.Request) (string, error)
 }
 
+// item is defined in app files with optional on_enter_actions, auth and roles
+// and one attribute named after its registered type (see RegisterItemType())
 type item struct {
 	//optional
 	OnEnter *Actions `json:"on_enter_actions,omitempty" doc:"Optional list of actions to take when entering the item"`
+	Auth    itemAuth `json:"auth,omitempty" doc:"Optional \"required\" to only allow authenticated users to enter the item"`
+	Roles   []string `json:"roles,omitempty" doc:"Optional roles of which the user needs one to enter the item, implies auth required"`
 
-	//union: one of the following is required
-	Menu   *menu         `json:"menu"`
-	Prompt *prompt       `json:"prompt"`
-	List   *list         `json:"list"`
-	Edit   *edit         `json:"edit"`
-	Next   *fileItemNext `json:"next" doc:"A series of actions to get to next"`
+	kind      string   //registered type name, e.g. "menu"
+	body      ItemType //parsed from the JSON attribute named kind
+	namespace string   //namespace of the file that defined the item, see qualifiedItemId()
 }
 
+func (i *item) UnmarshalJSON(data []byte) error {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	for _, name := range sortedRawKeys(obj) {
+		if name == "on_enter_actions" {
+			i.OnEnter = &Actions{}
+			if err := json.Unmarshal(obj[name], i.OnEnter); err != nil {
+				return errors.Wrapf(err, "invalid on_enter_actions")
+			}
+			continue
+		}
+		if name == "auth" {
+			if err := json.Unmarshal(obj[name], &i.Auth); err != nil {
+				return errors.Wrapf(err, "invalid auth")
+			}
+			continue
+		}
+		if name == "roles" {
+			if err := json.Unmarshal(obj[name], &i.Roles); err != nil {
+				return errors.Wrapf(err, "invalid roles")
+			}
+			continue
+		}
+		body, ok := newItemType(name)
+		if !ok {
+			continue //unknown attributes are handled in Load() as selected with WithUnknownAttributes()
+		}
+		if i.body != nil {
+			return errors.Errorf("has %s and %s instead of 1 of %s", i.kind, name, strings.Join(itemTypeNames(), "|"))
+		}
+		if err := json.Unmarshal(obj[name], body); err != nil {
+			return errors.Wrapf(err, "invalid %s", name)
+		}
+		i.kind = name
+		i.body = body
+	}
+	return nil
+} //item.UnmarshalJSON()
+
+func (i item) MarshalJSON() ([]byte, error) {
+	obj := map[string]interface{}{}
+	if i.OnEnter != nil {
+		obj["on_enter_actions"] = i.OnEnter
+	}
+	if i.Auth != "" {
+		obj["auth"] = i.Auth
+	}
+	if len(i.Roles) > 0 {
+		obj["roles"] = i.Roles
+	}
+	if i.body != nil {
+		obj[i.kind] = i.body
+	}
+	return json.Marshal(obj)
+} //item.MarshalJSON()
+
 func (i item) Validate(app App) error {
 	if i.OnEnter != nil {
 		if err := i.OnEnter.Validate(app); err != nil {
 			return errors.Wrapf(err, "invalid on_enter")
 		}
 	}
-
-	count := 0
-	if i.Menu != nil {
-		if err := i.Menu.Validate(); err != nil {
-			return errors.Wrapf(err, "invalid menu")
+	for index, role := range i.Roles {
+		if role == "" {
+			return errors.Errorf("invalid roles[%d] (empty)", index)
 		}
-		count++
 	}
-	if i.Prompt != nil {
-		if err := i.Prompt.Validate(); err != nil {
-			return errors.Wrapf(err, "invalid prompt")
-		}
-		count++
+	if i.body == nil {
+		return errors.Errorf("missing %s", strings.Join(itemTypeNames(), "|"))
 	}
-	if i.List != nil {
-		if err := i.List.Validate(app); err != nil {
-			return errors.Wrapf(err, "invalid list")
-		}
-		count++
+	if err := i.body.Validate(app); err != nil {
+		return errors.Wrapf(err, "invalid %s", i.kind)
 	}
-	if i.Edit != nil {
-		if err := i.Edit.Validate(app); err != nil {
-			return errors.Wrapf(err, "invalid edit")
+	return nil
+} //item.Validate()
+
+// itemNextList is a list of next steps in an item
+type itemNextList struct {
+	path    string  //JSON path in the item, e.g. "menu.items[1].next"
+	caption Caption //caption of the menu item/operation that leads to next, if any
+	next    fileItemNext
+}
+
+// itemNextLister is implemented by built-in item types with next steps
+// path of each list is relative to the type, or blank for the type itself
+type itemNextLister interface {
+	nextLists() []itemNextList
+}
+
+// nextLists returns all the next step lists in the item
+func (i item) nextLists() []itemNextList {
+	lister, ok := i.body.(itemNextLister)
+	if !ok {
+		return nil
+	}
+	lists := lister.nextLists()
+	for index := range lists {
+		if lists[index].path == "" {
+			lists[index].path = i.kind
+		} else {
+			lists[index].path = i.kind + "." + lists[index].path
 		}
-		count++
 	}
-	if i.Next != nil {
-		if err := i.Next.Validate(); err != nil {
-			return errors.Wrapf(err, "invalid next")
+	return lists
+} //item.nextLists()
+
+// refs returns all references to other items
+func (i item) refs() []ItemRef {
+	refs := []ItemRef{}
+	for _, list := range i.nextLists() {
+		label := ""
+		if ct, ok := list.caption[""]; ok {
+			label = ct.UnparsedTemplate
 		}
-		count++
-	}
-	if count == 0 {
-		return errors.Errorf("missing menu|prompt|list|edit|...")
+		refs = append(refs, list.next.refs(list.path, label, "")...)
 	}
-	if count > 1 {
-		return errors.Errorf("has %d instead of 1 of menu|prompt|list|...", count)
+	if typeRefs, ok := i.body.(ItemTypeRefs); ok {
+		for _, ref := range typeRefs.Refs() {
+			ref.Path = attrPath(i.kind, ref.Path)
+			ref.Id = qualifiedItemId(i.namespace, ref.Id)
+			refs = append(refs, ref)
+		}
 	}
-	return nil
-} //item.Validate()
+	return refs
+} //item.refs()
 
 func (item item) OnEnterActions() *Actions {
 	//do not return nil, else OnEnterActions().Execute() will fail
@@ -92,62 +177,35 @@ func (item item) OnEnterActions() *Actions {
 	return item.OnEnter
 }
 
-func (item item) Render(ctx context.Context, buffer io.Writer) (string, *PageData, error) {
-	if item.Menu != nil {
-		if pageData, err := item.Menu.Render(ctx, buffer); err != nil {
-			return "", nil, err
-		} else {
-			return "", pageData, nil
-		}
-	}
-	if item.Prompt != nil {
-		if pageData, err := item.Prompt.Render(ctx, buffer); err != nil {
-			return "", nil, err
-		} else {
-			return "", pageData, nil
-		}
-	}
-	if item.List != nil {
-		if pageData, err := item.List.Render(ctx, buffer); err != nil {
-			return "", nil, err
-		} else {
-			return "", pageData, nil
-		}
-	}
-	if item.Edit != nil {
-		if pageData, err := item.Edit.Render(ctx, buffer); err != nil {
-			return "", nil, err
-		} else {
-			return "", pageData, nil
-		}
+func (item item) Access() ItemAccess {
+	return ItemAccess{
+		AuthRequired: item.Auth == itemAuthRequired || len(item.Roles) > 0,
+		Roles:        item.Roles,
 	}
+}
 
-	if item.Next != nil {
-		//next sets the next item which should be rendered
-		nextItemId, err := item.Next.Execute(ctx)
-		if err != nil {
-			return "", nil, errors.Wrapf(err, "failed to execute action to determine next item")
-		}
-		return nextItemId, nil, nil //redirect
+func (item item) Render(ctx context.Context, buffer io.Writer) (string, *PageData, error) {
+	if item.body == nil {
+		return "", nil, errors.Errorf("cannot render item without type")
 	}
-	return "", nil, errors.Errorf("cannot render %+v", item)
+	nextItemId, pageData, err := item.body.Render(ctx, buffer)
+	return item.qualified(nextItemId), pageData, err
 }
 
 func (item item) Process(ctx context.Context, httpReq *http.Request) (string, error) {
-	//menu does not process a http POST
-	// if item.Menu != nil {
-	// 	return item.Menu.Process(ctx, httpReq)
-	// }
-
-	//todo: maybe list will post search filter?
-	// if item.List != nil {
-	// 	return item.List.Process(ctx, httpReq)
-	// }
-	if item.Prompt != nil {
-		return item.Prompt.Process(ctx, httpReq)
-	}
-	if item.Edit != nil {
-		return item.Edit.Process(ctx, httpReq)
-	}
-	return "", errors.Errorf("cannot process %+v", item)
+	if item.body == nil {
+		return "", errors.Errorf("cannot process item without type")
+	}
+	nextItemId, err := item.body.Process(ctx, httpReq)
+	return item.qualified(nextItemId), err
+}
+
+// qualified returns the full id of an item returned by a registered type
+// built-in types refer to items with next steps that are qualified in Load()
+// but other types use ids as written in the file, relative to its namespace
+func (item item) qualified(id string) string {
+	if _, ok := item.body.(itemNextLister); ok {
+		return id
+	}
+	return qualifiedItemId(item.namespace, id)
 }
@@ -0,0 +1,110 @@
+package app
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/go-msvc/errors"
+)
+
+// ItemType is the content of an item, e.g. a menu
+// in app files an item has one attribute named after its type with the type's own JSON:
+//
+//	{"on_enter_actions":[...], "auth":"required", "roles":[...], "<type name>":{...}}
+//
+// the built-in types are "menu", "prompt", "list", "edit" and "next"
+// item ids returned by the type are written as in the app file, i.e. relative to
+// the namespace of the file or absolute with a leading "/", e.g. "/home"
+type ItemType interface {
+	//Validate is called once after the item was parsed from JSON in Load()
+	Validate(app App) error
+
+	//Render writes the page into buffer, or returns nextItemId to redirect without a page
+	Render(ctx context.Context, buffer io.Writer) (nextItemId string, pageData *PageData, err error)
+
+	//Process is called on method POST and must return the next item id
+	Process(ctx context.Context, httpReq *http.Request) (nextItemId string, err error)
+}
+
+// ItemTypeRefs is optionally implemented by item types that refer to other items
+// so that Load() can check the references and tools can describe them
+// ItemRef.Path is relative to the type and ItemRef.Id is as written in the app file
+type ItemTypeRefs interface {
+	Refs() []ItemRef
+}
+
+// ItemTypeFactory returns a new empty value to parse the JSON of the item type into
+// it must return a pointer, e.g. func() app.ItemType { return &myType{} }
+type ItemTypeFactory func() ItemType
+
+var itemTypeNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// reservedItemAttributes are item attributes that cannot be used as item type names
+var reservedItemAttributes = map[string]bool{
+	"on_enter_actions": true,
+	"auth":             true,
+	"roles":            true,
+}
+
+var (
+	itemTypesMutex sync.Mutex
+	itemTypes      = map[string]ItemTypeFactory{}
+)
+
+func init() {
+	MustRegisterItemType("menu", func() ItemType { return &menu{} })
+	MustRegisterItemType("prompt", func() ItemType { return &prompt{} })
+	MustRegisterItemType("list", func() ItemType { return &list{} })
+	MustRegisterItemType("edit", func() ItemType { return &edit{} })
+	MustRegisterItemType("next", func() ItemType { return &nextItem{} })
+}
+
+// RegisterItemType adds an item type that can be used in app files
+// it must be called before app files that use it are loaded, e.g. in init()
+func RegisterItemType(name string, factory ItemTypeFactory) error {
+	if !itemTypeNameRegex.MatchString(name) || reservedItemAttributes[name] {
+		return errors.Errorf("invalid item type name \"%s\" (expect lower snake_case)", name)
+	}
+	if factory == nil {
+		return errors.Errorf("item type \"%s\" factory is nil", name)
+	}
+	itemTypesMutex.Lock()
+	defer itemTypesMutex.Unlock()
+	if _, ok := itemTypes[name]; ok {
+		return errors.Errorf("item type \"%s\" already registered", name)
+	}
+	itemTypes[name] = factory
+	return nil
+} //RegisterItemType()
+
+func MustRegisterItemType(name string, factory ItemTypeFactory) {
+	if err := RegisterItemType(name, factory); err != nil {
+		panic(err.Error())
+	}
+} //MustRegisterItemType()
+
+// newItemType returns a new value of the named type to parse JSON into
+func newItemType(name string) (ItemType, bool) {
+	itemTypesMutex.Lock()
+	defer itemTypesMutex.Unlock()
+	factory, ok := itemTypes[name]
+	if !ok {
+		return nil, false
+	}
+	return factory(), true
+}
+
+func itemTypeNames() []string {
+	itemTypesMutex.Lock()
+	defer itemTypesMutex.Unlock()
+	names := make([]string, 0, len(itemTypes))
+	for name := range itemTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
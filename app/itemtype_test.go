@@ -0,0 +1,105 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/go-msvc/errors"
+)
+
+// testLink is an item type that redirects to the item in "to"
+type testLink struct {
+	To string `json:"to"`
+}
+
+func init() {
+	MustRegisterItemType("test_link", func() ItemType { return &testLink{} })
+}
+
+func (link *testLink) Validate(app App) error {
+	if link.To == "" {
+		return errors.Errorf("missing to")
+	}
+	return nil
+}
+
+func (link *testLink) Render(ctx context.Context, buffer io.Writer) (string, *PageData, error) {
+	return link.To, nil, nil
+}
+
+func (link *testLink) Process(ctx context.Context, httpReq *http.Request) (string, error) {
+	return "", errors.Errorf("test_link does not handle POST")
+}
+
+func (link *testLink) Refs() []ItemRef {
+	return []ItemRef{{Path: "to", Id: link.To}}
+}
+
+func TestRegisterItemType(t *testing.T) {
+	factory := func() ItemType { return &testLink{} }
+	tests := []struct {
+		name    string
+		factory ItemTypeFactory
+	}{
+		{"", factory},
+		{"Link", factory},
+		{"my-link", factory},
+		{"1link", factory},
+		{"auth", factory},
+		{"on_enter_actions", factory},
+		{"no_factory", nil},
+		{"menu", factory},
+		{"test_link", factory},
+	}
+	for _, test := range tests {
+		if err := RegisterItemType(test.name, test.factory); err == nil {
+			t.Errorf("registered item type \"%s\"", test.name)
+		}
+	}
+	if _, ok := newItemType("no_factory"); ok {
+		t.Errorf("failed registration added the type")
+	}
+}
+
+func TestLoadItemType(t *testing.T) {
+	dir := testAppDir(t, map[string]string{
+		"app.json":       `{"home":` + testMenu("sub/link") + `}`,
+		"sub/items.json": `{"link":{"test_link":{"to":"page"}}, "page":` + testMenu("/home") + `}`,
+	})
+	a := New()
+	if err := a.Load(dir); err != nil {
+		t.Fatalf("failed to load: %+v", err)
+	}
+	info, _ := a.ItemInfo("sub/link")
+	expected := []ItemRef{{Path: "test_link.to", Id: "sub/page"}}
+	if info.Type != "test_link" || !reflect.DeepEqual(info.Refs, expected) {
+		t.Fatalf("type %s with refs %+v instead of %+v", info.Type, info.Refs, expected)
+	}
+	link, _ := a.GetItem("", "sub/link")
+	nextItemId, _, err := link.Render(context.Background(), bytes.NewBuffer(nil))
+	if err != nil || nextItemId != "sub/page" {
+		t.Fatalf("rendered next \"%s\" (%+v) instead of \"sub/page\"", nextItemId, err)
+	}
+
+	tests := []struct {
+		name     string
+		item     string
+		expected []string
+	}{
+		{"invalid", `{"test_link":{}}`, []string{`invalid item "home"`, `missing to`}},
+		{"dangling", `{"test_link":{"to":"missing"}}`, []string{`item "home" test_link.to refers to unknown item "missing"`}},
+		{"two types", `{"test_link":{"to":"home"}, "next":[{"item":"home"}]}`, []string{`has next and test_link instead of 1 of`}},
+		{"unknown type", `{"test_lnk":{"to":"home"}}`, []string{`item "home" has unknown attributes: home.test_lnk`}},
+		{"no type", `{"auth":"required"}`, []string{`invalid item "home"`, `missing edit|`}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := testAppDir(t, map[string]string{"app.json": `{"home":` + test.item + `}`})
+			testLoadError(t, New().Load(dir), test.expected)
+		})
+	}
+}
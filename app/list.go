@@ -2,10 +2,9 @@ package app
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"html/template"
 	"io"
+	"net/http"
 
 	"github.com/go-msvc/errors"
 	"github.com/google/uuid"
@@ -80,19 +79,20 @@ func (lc ListColumn) Validate() error {
 	return nil
 }
 
-func (list list) Render(ctx context.Context, buffer io.Writer) (*PageData, error) {
+func (list list) Render(ctx context.Context, buffer io.Writer) (string, *PageData, error) {
+	log := LoggerFrom(ctx)
 	lang := ctx.Value(CtxLang{}).(string)
 	session := ctx.Value(CtxSession{}).(*sessions.Session)
 
 	//clear items and then call actions to generate fresh list of items
 	delete(session.Values, "Items")
 	if err := list.GetItems.Execute(ctx); err != nil {
-		return nil, errors.Wrapf(err, "failed to get items")
+		return "", nil, errors.Wrapf(err, "failed to get items")
 	}
 	//items must be an array of structs or map[string]interface{}
 	columnList, ok := session.Values["Items"].(ColumnList)
 	if !ok {
-		return nil, errors.Errorf("Items (%T) not ColumnList", session.Values["Items"])
+		return "", nil, errors.Errorf("Items (%T) not ColumnList", session.Values["Items"])
 	}
 
 	//start prepare the template data so we can add info
@@ -103,7 +103,7 @@ func (list list) Render(ctx context.Context, buffer io.Writer) (*PageData, error
 	}
 	title, err := list.Title.Render(lang, sessionData(session))
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to render title")
+		return "", nil, errors.Wrapf(err, "failed to render title")
 	}
 	listTmplData := tmplDataForList{
 		Title:      title,
@@ -116,7 +116,7 @@ func (list list) Render(ctx context.Context, buffer io.Writer) (*PageData, error
 	for colIndex, col := range list.Options.Columns {
 		header, err := col.Header.Render(lang, sessionData(session))
 		if err != nil {
-			return nil, errors.Wrapf(err, "failed to render column[%d] header", colIndex)
+			return "", nil, errors.Wrapf(err, "failed to render column[%d] header", colIndex)
 		}
 		listTmplData.Columns = append(listTmplData.Columns, tmplDataForListColumn{
 			Header: header,
@@ -137,7 +137,7 @@ func (list list) Render(ctx context.Context, buffer io.Writer) (*PageData, error
 		for colIndex, col := range list.Options.Columns {
 			caption, err := col.Value.Render(lang, item)
 			if err != nil {
-				return nil, errors.Wrapf(err, "failed to render item[%d] caption for col[%d]", itemIndex, colIndex)
+				return "", nil, errors.Wrapf(err, "failed to render item[%d] caption for col[%d]", itemIndex, colIndex)
 			}
 			itemData.ColumnValues = append(itemData.ColumnValues, caption)
 		}
@@ -160,7 +160,7 @@ func (list list) Render(ctx context.Context, buffer io.Writer) (*PageData, error
 	for _, oper := range list.Operations {
 		caption, err := oper.Caption.Render(lang, sessionData(session))
 		if err != nil {
-			return nil, errors.Wrapf(err, "failed to render operation caption")
+			return "", nil, errors.Wrapf(err, "failed to render operation caption")
 		}
 		uuid := uuid.New().String()
 		pageData.Links[uuid] = oper.Next
@@ -172,22 +172,31 @@ func (list list) Render(ctx context.Context, buffer io.Writer) (*PageData, error
 		log.Debugf("Added operation: %+v", operTmpl)
 	}
 
-	{
-		log.Debugf("listTmplData: %+v", listTmplData)
-		j, _ := json.Marshal(listTmplData)
-		log.Debugf("listTmplData: %s", string(j))
+	tmplData := PageTmplData(ctx, listTmplData)
+	if err := renderPage(ctx, buffer, "list", tmplData); err != nil {
+		return "", nil, errors.Wrapf(err, "failed to exec list template")
 	}
+	return "", &pageData, nil
+} //list.Render()
 
-	tmplData := TmplData{
-		NavBar: TmplNavBar{
-			Email: "a@b.c", //todo...
-		},
-		Body: listTmplData,
+func (list list) Process(ctx context.Context, httpReq *http.Request) (string, error) {
+	//todo: maybe list will post search filter?
+	return "", errors.Errorf("list does not handle POST")
+}
+
+func (list list) nextLists() []itemNextList {
+	lists := []itemNextList{{path: "options.item_next", next: list.Options.ItemNext}}
+	for index, oper := range list.Operations {
+		lists = append(lists, itemNextList{path: fmt.Sprintf("operations[%d].next", index), caption: oper.Caption, next: oper.Next})
 	}
-	if err := listTmpl.ExecuteTemplate(buffer, "page", tmplData); err != nil {
-		return nil, errors.Wrapf(err, "failed to exec list template")
+	return lists
+}
+
+func (list list) funcNames() []string {
+	if list.GetItems == nil {
+		return nil
 	}
-	return &pageData, nil
+	return list.GetItems.funcNames()
 }
 
 type tmplDataForList struct {
@@ -211,16 +220,6 @@ type tmplDataForListOperation struct {
 	NextUUID string
 }
 
-var listTmpl *template.Template
-
-func init() {
-	var err error
-	listTmpl, err = LoadPageTemplates([]string{"list"})
-	if err != nil {
-		panic(fmt.Sprintf("failed to load list template: %+v", err))
-	}
-} //init()
-
 // list action function that sets "Items" must return ColumnList
 type ColumnList struct {
 	Items []ColumnItem
@@ -0,0 +1,273 @@
+package app
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-msvc/errors"
+)
+
+// definition is the set of items loaded from app files
+// it is not modified once loaded, but replaced as a whole on (re)load
+type definition struct {
+	version  string    //named version, or hash if not named
+	named    bool      //version was named with LoadVersion() and keeps its name on Reload()
+	hash     string    //hash of the files, to detect changes
+	retireAt time.Time //when sessions may no longer use this version (zero while in use)
+	patterns []string  //files/dirs/globs it was loaded from
+	items    map[string]AppItem
+	sources  map[string]itemSource //file and line where each item was defined
+}
+
+// loadDefinition reads and validates all items from the files
+// version is optional and defaults to a short hash of the file contents
+func (app *app) loadDefinition(version string, patterns []string) (*definition, error) {
+	files, err := findAppFiles(patterns)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find app files")
+	}
+	if len(files) == 0 {
+		return nil, errors.Errorf("no app files found in %v", patterns)
+	}
+
+	def := &definition{
+		patterns: patterns,
+		items:    map[string]AppItem{},
+		sources:  map[string]itemSource{},
+	}
+	loaded := map[string]loadedItem{}
+	hash := sha256.New()
+	for _, file := range files {
+		fileItems, err := app.readAppFile(file, hash)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read app file %s", file.filename)
+		}
+		for _, fileItem := range fileItems {
+			if existing, ok := loaded[fileItem.id]; ok {
+				return nil, errors.Errorf("%s: duplicate item id \"%s\" already defined in %s", fileItem.source, fileItem.id, existing.source)
+			}
+			loaded[fileItem.id] = fileItem
+		}
+	}
+
+	ids := make([]string, 0, len(loaded))
+	for id := range loaded {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids) //report the same error first when there are many
+	for _, id := range ids {
+		loadedItem := loaded[id]
+		if err := loadedItem.item.Validate(app); err != nil {
+			return nil, errors.Wrapf(err, "%s: invalid item \"%s\"", loadedItem.source, id)
+		}
+		def.items[id] = loadedItem.item
+		def.sources[id] = loadedItem.source
+	}
+
+	//check references between all items
+	if err := validateRefs(def.items, def.sources, app.danglingRefs); err != nil {
+		return nil, err
+	}
+	def.hash = hex.EncodeToString(hash.Sum(nil))[:12]
+	def.version = version
+	def.named = version != ""
+	if !def.named {
+		def.version = def.hash
+	}
+	log.Debugf("Loaded version %s with %d items from %d files", def.version, len(def.items), len(files))
+	return def, nil
+} //app.loadDefinition()
+
+// appFile is a JSON file with items to load into the app
+// items in the file are prefixed with the namespace (if not blank)
+type appFile struct {
+	filename  string
+	namespace string
+}
+
+// itemSource is where an item was defined, used to report errors
+type itemSource struct {
+	filename string
+	line     int
+}
+
+func (s itemSource) String() string {
+	return fmt.Sprintf("%s:%d", s.filename, s.line)
+}
+
+type loadedItem struct {
+	id     string
+	source itemSource
+	item   item
+}
+
+// findAppFiles expands the patterns into a list of files to load
+// a directory is walked for all *.json files and files in sub directories
+// get the relative sub directory path as namespace, e.g. "<dir>/profile/edit.json"
+// defines items "profile/<id>"
+func findAppFiles(patterns []string) ([]appFile, error) {
+	files := []appFile{}
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid pattern \"%s\"", pattern)
+		}
+		if len(matches) == 0 {
+			return nil, errors.Errorf("\"%s\" not found", pattern)
+		}
+		sort.Strings(matches)
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, errors.Wrapf(err, "cannot access %s", match)
+			}
+			if !info.IsDir() {
+				files = append(files, appFile{filename: match, namespace: ""})
+				continue
+			}
+			dirFiles, err := findAppDirFiles(match)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to read directory %s", match)
+			}
+			files = append(files, dirFiles...)
+		}
+	}
+	return files, nil
+} //findAppFiles()
+
+func findAppDirFiles(dir string) ([]appFile, error) {
+	files := []appFile{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		relDir, err := filepath.Rel(dir, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		namespace := ""
+		if relDir != "." {
+			namespace = filepath.ToSlash(relDir)
+			if !namespacedItemIdRegex.MatchString(namespace) {
+				return errors.Errorf("%s: invalid namespace \"%s\" (expect lower alnum with dashes for each sub directory)", path, namespace)
+			}
+		}
+		files = append(files, appFile{filename: path, namespace: namespace})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+} //findAppDirFiles()
+
+// readAppFile parses the items in a file
+// the file is decoded one item at a time to report the line where an item is defined
+// and to detect duplicate ids which a plain map decode would silently overwrite
+// unknown attributes are handled as selected with WithUnknownAttributes()
+// the file name and content are written to hash to identify the version
+func (app *app) readAppFile(file appFile, hash io.Writer) ([]loadedItem, error) {
+	data, err := os.ReadFile(file.filename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read file")
+	}
+	fmt.Fprintf(hash, "%s:%s:%d\n", file.filename, file.namespace, len(data))
+	hash.Write(data)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, errors.Errorf("%s:%d: expected JSON object with items", file.filename, lineAt(data, decoder.InputOffset()))
+	}
+
+	items := []loadedItem{}
+	defined := map[string]itemSource{}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s:%d: invalid JSON", file.filename, lineAt(data, decoder.InputOffset()))
+		}
+		name := token.(string) //object keys are always strings
+		source := itemSource{filename: file.filename, line: lineAt(data, decoder.InputOffset())}
+		if !itemIdRegex.MatchString(name) {
+			return nil, errors.Errorf("%s: missing/invalid item id \"%s\" (expect lower alnum with dashes, e.g. \"my-item1-loader\")", source, name)
+		}
+		if existing, ok := defined[name]; ok {
+			return nil, errors.Errorf("%s: duplicate item id \"%s\" already defined in %s", source, name, existing)
+		}
+		defined[name] = source
+
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, errors.Wrapf(err, "%s: invalid JSON for item \"%s\"", source, name)
+		}
+		var fileItem item
+		if err := json.Unmarshal(raw, &fileItem); err != nil {
+			return nil, errors.Wrapf(err, "%s: failed to parse item \"%s\"", source, name)
+		}
+		if app.unknownAttributes != UnknownAttributesIgnore {
+			var value interface{}
+			if err := json.Unmarshal(raw, &value); err != nil {
+				return nil, errors.Wrapf(err, "%s: failed to parse item \"%s\"", source, name)
+			}
+			if unknown := fileItem.unknownAttributes(value, name); len(unknown) > 0 {
+				if app.unknownAttributes == UnknownAttributesFail {
+					return nil, errors.Errorf("%s: item \"%s\" has unknown attributes: %s", source, name, strings.Join(unknown, ", "))
+				}
+				for _, path := range unknown {
+					log.Errorf("%s: item \"%s\" has unknown attribute %s (ignored)", source, name, path)
+				}
+			}
+		}
+
+		//make all item references in the file absolute
+		for _, list := range fileItem.nextLists() {
+			list.next.walk(func(step fileItemNextStep) {
+				if step.Item != nil {
+					*step.Item = fileItemNextItem(qualifiedItemId(file.namespace, string(*step.Item)))
+				}
+			})
+		}
+		fileItem.namespace = file.namespace
+		items = append(items, loadedItem{
+			id:     qualifiedItemId(file.namespace, name),
+			source: source,
+			item:   fileItem,
+		})
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, errors.Wrapf(err, "%s:%d: invalid JSON", file.filename, lineAt(data, decoder.InputOffset()))
+	}
+	return items, nil
+} //readAppFile()
+
+// qualifiedItemId resolves an item id used in a file with the given namespace
+// ids starting with "/" are absolute, e.g. "/home", all others are relative to the namespace
+func qualifiedItemId(namespace, id string) string {
+	if strings.HasPrefix(id, "/") {
+		return id[1:]
+	}
+	if namespace == "" || id == "" {
+		return id
+	}
+	return namespace + "/" + id
+}
+
+// lineAt returns the 1-based line number of the offset in data
+func lineAt(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
@@ -0,0 +1,138 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testMenu is the JSON of a menu item with one menu item to next
+func testMenu(next string) string {
+	return `{"menu":{"title":{"":"Test"}, "items":[{"caption":{"":"Next"}, "next":[{"item":"` + next + `"}]}]}}`
+}
+
+// testAppDir writes the files (name relative to the dir -> content) in a new temp dir
+func testAppDir(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatalf("failed to create dir: %+v", err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %+v", name, err)
+		}
+	}
+	return dir
+}
+
+// testLoadError checks that err contains all the expected text, or is nil if none expected
+func testLoadError(t *testing.T, err error, expected []string) {
+	t.Helper()
+	if len(expected) == 0 {
+		if err != nil {
+			t.Fatalf("failed to load: %+v", err)
+		}
+		return
+	}
+	if err == nil {
+		t.Fatalf("loaded instead of failing with %q", expected)
+	}
+	for _, text := range expected {
+		if !strings.Contains(err.Error(), text) {
+			t.Errorf("error does not contain %q: %s", text, err.Error())
+		}
+	}
+}
+
+func TestLoadNamespaces(t *testing.T) {
+	dir := testAppDir(t, map[string]string{
+		"app.json":           `{"home":` + testMenu("profile/edit") + `}`,
+		"profile/items.json": `{"edit":` + testMenu("view") + `, "view":` + testMenu("/home") + `}`,
+		"readme.txt":         `not loaded`,
+	})
+	a := New()
+	if err := a.Load(dir); err != nil {
+		t.Fatalf("failed to load: %+v", err)
+	}
+	if ids := a.ItemIds(); !reflect.DeepEqual(ids, []string{"home", "profile/edit", "profile/view"}) {
+		t.Fatalf("loaded %v", ids)
+	}
+	for id, expected := range map[string]string{
+		"home":         "profile/edit",
+		"profile/edit": "profile/view",
+		"profile/view": "home",
+	} {
+		info, _ := a.ItemInfo(id)
+		if len(info.Refs) != 1 || info.Refs[0].Id != expected {
+			t.Errorf("%s refers to %+v instead of %s", id, info.Refs, expected)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected []string
+	}{
+		{
+			name:     "duplicate in file",
+			files:    map[string]string{"app.json": "{\n\"home\":" + testMenu("home") + ",\n\"home\":" + testMenu("home") + "\n}"},
+			expected: []string{`app.json:3: duplicate item id "home" already defined in`, `app.json:2`},
+		},
+		{
+			name: "duplicate across files",
+			files: map[string]string{
+				"a.json": `{"home":` + testMenu("other") + `, "other":` + testMenu("home") + `}`,
+				"b.json": "{\n\"other\":" + testMenu("home") + "}",
+			},
+			expected: []string{`b.json:2: duplicate item id "other" already defined in`, `a.json:1`},
+		},
+		{
+			name: "same id in namespaces",
+			files: map[string]string{
+				"app.json":      `{"home":` + testMenu("a/edit") + `}`,
+				"a/items.json":  `{"edit":` + testMenu("/b/edit") + `}`,
+				"b/items.json":  `{"edit":` + testMenu("c/edit") + `}`,
+				"b/c/more.json": `{"edit":` + testMenu("/home") + `}`,
+			},
+			expected: nil, //a/edit, b/edit and b/c/edit
+		},
+		{
+			name: "relative ref to other namespace",
+			files: map[string]string{
+				"app.json":     `{"home":` + testMenu("a/edit") + `}`,
+				"a/items.json": `{"edit":` + testMenu("home") + `}`,
+			},
+			expected: []string{`item "a/edit" menu.items[0].next[0].item refers to unknown item "a/home"`},
+		},
+		{
+			name:     "invalid id",
+			files:    map[string]string{"app.json": `{"Home":` + testMenu("home") + `}`},
+			expected: []string{`missing/invalid item id "Home"`},
+		},
+		{
+			name:     "invalid namespace",
+			files:    map[string]string{"app.json": `{"home":` + testMenu("home") + `}`, "My Items/x.json": `{}`},
+			expected: []string{`invalid namespace "My Items"`},
+		},
+		{
+			name:     "not an object",
+			files:    map[string]string{"app.json": `["home"]`},
+			expected: []string{`app.json:1: expected JSON object with items`},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := New().Load(testAppDir(t, test.files))
+			testLoadError(t, err, test.expected)
+		})
+	}
+
+	if err := New().Load(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("loaded missing file")
+	}
+}
@@ -0,0 +1,74 @@
+package app
+
+import (
+	"context"
+
+	"github.com/go-msvc/logger"
+)
+
+// log is the package logger for lines outside of web requests, it logs info
+// until SetLogLevel() is called, e.g. by web.New() with LOG_LEVEL
+var log = logger.New()
+
+func init() {
+	log.SetLevel(logger.LevelInfo)
+}
+
+// SetLogLevel of the package logger
+func SetLogLevel(level logger.Level) {
+	log.SetLevel(level)
+}
+
+// CtxLogger is the context key of the *RequestLogger while handling a web request
+type CtxLogger struct{}
+
+// RequestLogger stamps each log line of a request with fields like request id, device,
+// item and app version, it is kept in the context as a pointer so that fields can be
+// updated while the request navigates from item to item
+type RequestLogger struct {
+	logger.Logger
+	redact map[string]bool
+}
+
+// NewRequestLogger makes a request logger from l
+// values of the redactKeys are not logged, e.g. "NationalId"
+func NewRequestLogger(l logger.Logger, redactKeys []string) *RequestLogger {
+	rl := &RequestLogger{
+		Logger: l,
+		redact: map[string]bool{},
+	}
+	for _, key := range redactKeys {
+		rl.redact[key] = true
+	}
+	return rl
+}
+
+// Set the field on all following log lines of the request
+func (rl *RequestLogger) Set(name string, value interface{}) {
+	rl.Logger = rl.Logger.With(name, value)
+}
+
+// Redacted returns the value to log for a session key
+func (rl *RequestLogger) Redacted(key string, value interface{}) interface{} {
+	if rl.redact[key] {
+		return "<redacted>"
+	}
+	return value
+}
+
+// LoggerFrom returns the request logger in ctx,
+// or the package logger when ctx is not from a web request
+func LoggerFrom(ctx context.Context) logger.Logger {
+	if rl, ok := ctx.Value(CtxLogger{}).(*RequestLogger); ok {
+		return rl
+	}
+	return log
+}
+
+// logValue is the value of a session key to log, redacted if configured so in the request logger
+func logValue(ctx context.Context, key string, value interface{}) interface{} {
+	if rl, ok := ctx.Value(CtxLogger{}).(*RequestLogger); ok {
+		return rl.Redacted(key, value)
+	}
+	return value
+}
@@ -3,7 +3,6 @@ package app
 import (
 	"context"
 	"fmt"
-	"html/template"
 	"io"
 	"net/http"
 
@@ -17,7 +16,7 @@ type menu struct {
 	Items []menuItem `json:"items"`
 }
 
-func (menu menu) Validate() error {
+func (menu menu) Validate(app App) error {
 	if err := menu.Title.Validate(true); err != nil {
 		return errors.Wrapf(err, "invalid title")
 	}
@@ -35,7 +34,7 @@ func (menu menu) Validate() error {
 //todo: make menu with sub menus that can expand and collapse with headings
 //the rendering template can display it any way required...
 
-func (menu menu) Render(ctx context.Context, buffer io.Writer) (*PageData, error) {
+func (menu menu) Render(ctx context.Context, buffer io.Writer) (string, *PageData, error) {
 	//for each menu item, generate a uuid stored in the session
 	//which are used in the URL and avoids a user to manipulate
 	//the app by changing URLs
@@ -47,7 +46,7 @@ func (menu menu) Render(ctx context.Context, buffer io.Writer) (*PageData, error
 	session := ctx.Value(CtxSession{}).(*sessions.Session)
 	title, err := menu.Title.Render(lang, sessionData(session))
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to render title")
+		return "", nil, errors.Wrapf(err, "failed to render title")
 	}
 	menuTmplData := tmplDataForMenu{
 		Title: title,
@@ -56,7 +55,7 @@ func (menu menu) Render(ctx context.Context, buffer io.Writer) (*PageData, error
 	for _, item := range menu.Items {
 		caption, err := item.Caption.Render(lang, sessionData(session))
 		if err != nil {
-			return nil, errors.Wrapf(err, "failed to render item caption")
+			return "", nil, errors.Wrapf(err, "failed to render item caption")
 		}
 		uuid := uuid.New().String()
 		pageData.Links[uuid] = item.Next
@@ -67,20 +66,23 @@ func (menu menu) Render(ctx context.Context, buffer io.Writer) (*PageData, error
 			})
 	}
 
-	tmplData := TmplData{
-		NavBar: TmplNavBar{
-			Email: "a@b.c", //todo...
-		},
-		Body: menuTmplData,
+	tmplData := PageTmplData(ctx, menuTmplData)
+	if err := renderPage(ctx, buffer, "menu", tmplData); err != nil {
+		return "", nil, errors.Wrapf(err, "failed to exec menu template")
 	}
-	if err := menuTmpl.ExecuteTemplate(buffer, "page", tmplData); err != nil {
-		return nil, errors.Wrapf(err, "failed to exec menu template")
-	}
-	return &pageData, nil
+	return "", &pageData, nil
 } //menu.Render()
 
-func (menu menu) Process(ctx context.Context, httpReq *http.Request) error {
-	return errors.Errorf("menu does not handle POST")
+func (menu menu) Process(ctx context.Context, httpReq *http.Request) (string, error) {
+	return "", errors.Errorf("menu does not handle POST")
+}
+
+func (menu menu) nextLists() []itemNextList {
+	lists := []itemNextList{}
+	for index, menuItem := range menu.Items {
+		lists = append(lists, itemNextList{path: fmt.Sprintf("items[%d].next", index), caption: menuItem.Caption, next: menuItem.Next})
+	}
+	return lists
 }
 
 type menuItem struct {
@@ -110,21 +112,18 @@ type tmplDataForMenuItem struct {
 
 // generic
 type TmplData struct {
-	NavBar TmplNavBar
-	Body   interface{} //depends on the page
+	NavBar      TmplNavBar
+	Stylesheets []string    //URLs of stylesheets to link in the page
+	FormError   string      //why the posted form was not accepted
+	Body        interface{} //depends on the page
 }
 type TmplNavBar struct {
 	//Items...
-	//User  *TmplUser
-	Email string
-}
+	Email string //of the logged in user, blank when not logged in
+	Phone string //of the logged in user with AUTH=otp
+	Name  string
 
-var menuTmpl *template.Template
+	LoginEnabled bool //links to login and register when not logged in
 
-func init() {
-	var err error
-	menuTmpl, err = LoadPageTemplates([]string{"menu"})
-	if err != nil {
-		panic(fmt.Sprintf("failed to load menu template: %+v", err))
-	}
-} //init()
+	FormTokens FormTokens //only CSRF, for the logout form
+}
@@ -0,0 +1,40 @@
+package app
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/jansemmelink/goweb1/metrics"
+)
+
+var (
+	funcCalls    = metrics.NewCounter("goweb1_func_calls_total", "Calls to registered app funcs.", "func")
+	funcErrors   = metrics.NewCounter("goweb1_func_errors_total", "Calls to registered app funcs that returned an error.", "func")
+	funcDuration = metrics.NewHistogram("goweb1_func_duration_seconds", "Duration of calls to registered app funcs.", metrics.DurationBuckets, "func")
+)
+
+// call the registered func with ctx, the user if the func takes one and the optional req,
+// and record the call in metrics
+// the last result is always the error
+func (f *AppFunc) call(ctx context.Context, req ...reflect.Value) ([]reflect.Value, error) {
+	args := []reflect.Value{reflect.ValueOf(ctx)}
+	if f.takesUser {
+		user := UserFrom(ctx)
+		if user == nil {
+			return nil, NewError(KindAuthRequired, "%s() requires an authenticated user", f.name)
+		}
+		userCopy := *user //the func cannot change the user of the session
+		userCopy.Roles = append([]string(nil), user.Roles...)
+		args = append(args, reflect.ValueOf(&userCopy))
+	}
+	args = append(args, req...)
+	start := time.Now()
+	results := f.funcValue.Call(args)
+	funcDuration.Observe(time.Since(start).Seconds(), f.name)
+	funcCalls.Inc(f.name)
+	if !results[len(results)-1].IsNil() {
+		funcErrors.Inc(f.name)
+	}
+	return results, nil
+} //AppFunc.call()
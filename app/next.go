@@ -2,6 +2,9 @@ package app
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"net/http"
 
 	"github.com/go-msvc/data"
 	"github.com/go-msvc/errors"
@@ -11,6 +14,30 @@ import (
 
 type fileItemNext []fileItemNextStep
 
+// nextItem is the "next" item type without a page,
+// it executes the steps and redirects to the next item
+type nextItem fileItemNext
+
+func (next nextItem) Validate(app App) error {
+	return fileItemNext(next).Validate()
+}
+
+func (next nextItem) Render(ctx context.Context, buffer io.Writer) (string, *PageData, error) {
+	nextItemId, err := fileItemNext(next).Execute(ctx)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "failed to execute action to determine next item")
+	}
+	return nextItemId, nil, nil //redirect
+}
+
+func (next nextItem) Process(ctx context.Context, httpReq *http.Request) (string, error) {
+	return "", errors.Errorf("next does not handle POST")
+}
+
+func (next nextItem) nextLists() []itemNextList {
+	return []itemNextList{{path: "", next: fileItemNext(next)}}
+}
+
 func (next fileItemNext) Validate() error {
 	if len(next) == 0 {
 		return errors.Errorf("missing")
@@ -26,7 +53,44 @@ func (next fileItemNext) Validate() error {
 	return nil
 }
 
+// walk calls fn for each step, including the steps inside if/then/else
+func (next fileItemNext) walk(fn func(step fileItemNextStep)) {
+	for _, step := range next {
+		fn(step)
+		if step.If != nil {
+			step.If.Then.walk(fn)
+			step.If.Else.walk(fn)
+		}
+	}
+}
+
+// refs returns the item references in the steps
+// path is the JSON path of the steps, label describes how the user gets here (may be blank)
+// and cond is the condition under which these steps are executed (blank if always)
+func (next fileItemNext) refs(path, label, cond string) []ItemRef {
+	refs := []ItemRef{}
+	for stepIndex, step := range next {
+		stepPath := fmt.Sprintf("%s[%d]", path, stepIndex)
+		if step.Item != nil {
+			refs = append(refs, ItemRef{Path: stepPath + ".item", Label: label, Cond: cond, Id: string(*step.Item)})
+		}
+		if step.If != nil {
+			refs = append(refs, step.If.Then.refs(stepPath+".if.then", label, andCond(cond, step.If.Expr))...)
+			refs = append(refs, step.If.Else.refs(stepPath+".if.else", label, andCond(cond, "!("+step.If.Expr+")"))...)
+		}
+	}
+	return refs
+}
+
+func andCond(cond, expr string) string {
+	if cond == "" {
+		return expr
+	}
+	return cond + " && " + expr
+}
+
 func (next fileItemNext) Execute(ctx context.Context) (nextItemId string, err error) {
+	log := LoggerFrom(ctx)
 	session := ctx.Value(CtxSession{}).(*sessions.Session)
 	for stepIndex, step := range next {
 		if step.Set != nil {
@@ -38,7 +102,7 @@ func (next fileItemNext) Execute(ctx context.Context) (nextItemId string, err er
 			value, ok := session.Values[step.Set.ValueStr]
 			if ok {
 				session.Values[name] = value
-				log.Debugf("DIRECT SET(%s)=\"%s\"", name, value)
+				log.Debugf("DIRECT SET(%s)=\"%s\"", name, logValue(ctx, name, value))
 			} else {
 				//array dereference...
 				value, err = data.Get(sessionData(session), step.Set.ValueStr)
@@ -48,13 +112,13 @@ func (next fileItemNext) Execute(ctx context.Context) (nextItemId string, err er
 				}
 			}
 			// value := ...step.Set.Value.Rendered(sessionData(session))
-			log.Debugf("SET(%s)=(%T)\"%v\"", name, value, value)
+			log.Debugf("SET(%s)=(%T)\"%v\"", name, value, logValue(ctx, name, value))
 			session.Values[name] = value
 			continue
 		} //if SET
 		if step.If != nil {
 			log.Debugf("next If: %+v", step.If)
-			condValue, err := step.If.expr.Eval(sessionForExpression(session))
+			condValue, err := step.If.expr.Eval(sessionForExpression(ctx, session))
 			if err != nil {
 				return "", errors.Wrapf(err, "failed to eval the expression")
 			}
@@ -100,7 +164,7 @@ func (next fileItemNext) Execute(ctx context.Context) (nextItemId string, err er
 type fileItemNextStep struct {
 	Item *fileItemNextItem `json:"item,omitempty" doc:"Value is next item id"`
 	Set  *fileItemSet      `json:"set,omitempty"`
-	If   *fileItemIf       `json:"if,omitemptu" doc:"Conditional step"`
+	If   *fileItemIf       `json:"if,omitempty" doc:"Conditional step"`
 }
 
 type fileItemNextItem string
@@ -134,12 +198,13 @@ func (next fileItemNextStep) Validate() error {
 	return nil
 }
 
-func sessionForExpression(s *sessions.Session) expression.IContext {
-	return x{s: s}
+func sessionForExpression(ctx context.Context, s *sessions.Session) expression.IContext {
+	return x{ctx: ctx, s: s}
 }
 
 type x struct {
-	s *sessions.Session
+	ctx context.Context
+	s   *sessions.Session
 }
 
 func (x x) Get(name string) interface{} {
@@ -147,11 +212,11 @@ func (x x) Get(name string) interface{} {
 	if !ok {
 		value = ""
 	}
-	log.Debugf("GETTING %s -> (%T)%+v", name, value, value)
+	LoggerFrom(x.ctx).Debugf("GETTING %s -> (%T)%+v", name, value, logValue(x.ctx, name, value))
 	return value
 }
 
 func (x x) Set(name string, value interface{}) {
-	log.Debugf("SETTING %s = (%T)%+v", name, value, value)
+	LoggerFrom(x.ctx).Debugf("SETTING %s = (%T)%+v", name, value, logValue(x.ctx, name, value))
 	x.s.Values[name] = value
 }
@@ -3,7 +3,6 @@ package app
 import (
 	"context"
 	"fmt"
-	"html/template"
 	"io"
 	"net/http"
 	"regexp"
@@ -19,7 +18,7 @@ type prompt struct {
 	//todo: validation rules/function with args
 }
 
-func (prompt *prompt) Validate() error {
+func (prompt *prompt) Validate(app App) error {
 	if err := prompt.Caption.Validate(false); err != nil {
 		return errors.Wrapf(err, "invalid caption")
 	}
@@ -32,36 +31,33 @@ func (prompt *prompt) Validate() error {
 	return nil
 }
 
-func (prompt prompt) Render(ctx context.Context, buffer io.Writer) (*PageData, error) {
+func (prompt prompt) Render(ctx context.Context, buffer io.Writer) (string, *PageData, error) {
 	lang := ctx.Value(CtxLang{}).(string)
 	session := ctx.Value(CtxSession{}).(*sessions.Session)
 	caption, err := prompt.Caption.Render(lang, sessionData(session))
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to render caption")
+		return "", nil, errors.Wrapf(err, "failed to render caption")
 	}
 	promptTmplData := tmplDataForPrompt{
-		Caption: caption,
+		Caption:    caption,
+		FormTokens: formTokens(ctx),
 	}
-	tmplData := TmplData{
-		NavBar: TmplNavBar{
-			Email: "a@b.c", //todo... phone/user name?
-		},
-		Body: promptTmplData,
+	promptTmplData.Value, _ = formValue(ctx, "SubmittedValue") //input that was not accepted
+	tmplData := PageTmplData(ctx, promptTmplData)
+	if err := renderPage(ctx, buffer, "prompt", tmplData); err != nil {
+		return "", nil, errors.Wrapf(err, "failed to exec prompt template")
 	}
-	if err := promptTmpl.ExecuteTemplate(buffer, "page", tmplData); err != nil {
-		return nil, errors.Wrapf(err, "failed to exec prompt template")
-	}
-	return nil, nil
+	return "", nil, nil
 } //prompt.Render()
 
 func (prompt prompt) Process(ctx context.Context, httpReq *http.Request) (string, error) {
 	httpReq.ParseForm()
 	submittedValueList, ok := httpReq.Form["SubmittedValue"] //"SubmittedValue" is used in prompt.tmpl...
 	if !ok {
-		return "", errors.Errorf("form did not post expected value")
+		return "", NewError(KindValidation, "form did not post expected value")
 	}
 	if len(submittedValueList) != 1 {
-		return "", errors.Errorf("form post len(SubmittedValue)=%d", len(submittedValueList))
+		return "", NewError(KindValidation, "form post len(SubmittedValue)=%d", len(submittedValueList))
 	}
 	session := ctx.Value(CtxSession{}).(*sessions.Session)
 	renderedName := prompt.Name.Rendered(sessionData(session))
@@ -69,32 +65,28 @@ func (prompt prompt) Process(ctx context.Context, httpReq *http.Request) (string
 		return "", errors.Errorf("prompt invalid name(%s).rendered->\"%s\"", prompt.Name.UnparsedTemplate, renderedName)
 	}
 
-	log.Debugf("Set %s=\"%s\"", renderedName, submittedValueList[0])
+	LoggerFrom(ctx).Debugf("Set %s=\"%s\"", renderedName, logValue(ctx, renderedName, submittedValueList[0]))
 	session.Values[renderedName] = submittedValueList[0]
 
 	//process next steps to return nextId or error
 	return prompt.Next.Execute(ctx)
 } //prompt.Process()
 
+func (prompt prompt) nextLists() []itemNextList {
+	return []itemNextList{{path: "next", next: prompt.Next}}
+}
+
 type tmplDataForPrompt struct {
-	Caption string
-	Name    string
+	Caption    string
+	Name       string
+	Value      string
+	FormTokens FormTokens
 }
 
 const fieldNamePattern = `[A-Z][a-zA-Z0-9]*` //CamelCase
 
 var fieldNameRegex = regexp.MustCompile("^" + fieldNamePattern + "$")
 
-var promptTmpl *template.Template
-
-func init() {
-	var err error
-	promptTmpl, err = LoadPageTemplates([]string{"prompt"})
-	if err != nil {
-		panic(fmt.Sprintf("failed to load prompt template: %+v", err))
-	}
-} //init()
-
 func sessionData(s *sessions.Session) map[string]interface{} {
 	data := map[string]interface{}{}
 	for n, v := range s.Values {
@@ -0,0 +1,172 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-msvc/errors"
+)
+
+// ItemRef is a reference from an item to a next item
+type ItemRef struct {
+	Path  string //JSON path of the reference in the item, e.g. "menu.items[1].next[0].item"
+	Label string //default caption of the menu item/operation leading to next (blank if none)
+	Cond  string //condition for the reference when inside if/then/else (blank if always)
+	Id    string //referenced item id
+}
+
+// DanglingRefs selects what Load does when items refer to unknown items
+// (e.g. an app still under construction)
+type DanglingRefs int
+
+const (
+	DanglingRefsFail   DanglingRefs = iota //Load fails (default)
+	DanglingRefsWarn                       //Load logs each reference to an unknown item and continues
+	DanglingRefsIgnore                     //Load ignores references to unknown items
+)
+
+// itemRefs is implemented by items that refer to other items
+type itemRefs interface {
+	refs() []ItemRef
+}
+
+// validateRefs checks the references between all items once they are loaded
+// references to unknown items are handled as selected with WithDanglingRefs(),
+// so an app under construction can still load
+// it returns an error for redirect cycles that are always followed,
+// logs cycles that depend on conditions
+// and logs items that cannot be reached from "home"
+func validateRefs(items map[string]AppItem, sources map[string]itemSource, dangling DanglingRefs) error {
+	problems := []string{}
+	refsById := map[string][]ItemRef{}
+	for _, id := range sortedItemIds(items) {
+		refItem, ok := items[id].(itemRefs)
+		if !ok {
+			continue
+		}
+		refsById[id] = refItem.refs()
+		for _, ref := range refsById[id] {
+			if _, ok := items[ref.Id]; ok {
+				continue
+			}
+			switch dangling {
+			case DanglingRefsFail:
+				problems = append(problems, fmt.Sprintf("%s: item \"%s\" %s refers to unknown item \"%s\"", sources[id], id, ref.Path, ref.Id))
+			case DanglingRefsWarn:
+				log.Errorf("%s: item \"%s\" %s refers to unknown item \"%s\" (not found when selected)", sources[id], id, ref.Path, ref.Id)
+			}
+		}
+	}
+
+	//redirect cycles: items with only next steps are rendered by redirecting
+	//to the next item, so a cycle among them will never display a page
+	//a cycle through if/then/else may be broken by its conditions at runtime
+	for _, cycle := range redirectCycles(items, refsById, false) {
+		problems = append(problems, fmt.Sprintf("%s: redirect cycle %s", sources[cycle.ids[0]], strings.Join(cycle.ids, " -> ")))
+	}
+	for _, cycle := range redirectCycles(items, refsById, true) {
+		if cycle.conditional {
+			log.Errorf("%s: conditional redirect cycle %s", sources[cycle.ids[0]], strings.Join(cycle.ids, " -> "))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.Errorf("%d invalid item references:\n\t%s", len(problems), strings.Join(problems, "\n\t"))
+	}
+
+	//unreachable items are not an error, they may be entered from code
+	if _, ok := items["home"]; !ok {
+		log.Errorf("missing item \"home\" where sessions start")
+		return nil
+	}
+	//the "error" item is entered by the web server when a request fails
+	reached := map[string]bool{"home": true, "error": true}
+	todo := []string{"home", "error"}
+	for len(todo) > 0 {
+		id := todo[0]
+		todo = todo[1:]
+		for _, ref := range refsById[id] {
+			if !reached[ref.Id] {
+				reached[ref.Id] = true
+				todo = append(todo, ref.Id)
+			}
+		}
+	}
+	for _, id := range sortedItemIds(items) {
+		if !reached[id] {
+			log.Errorf("%s: item \"%s\" cannot be reached from \"home\"", sources[id], id)
+		}
+	}
+	return nil
+} //validateRefs()
+
+// redirectCycle is a list of item ids that starts and ends with the same id
+type redirectCycle struct {
+	ids         []string
+	conditional bool //true if any step in the cycle is inside if/then/else
+}
+
+// redirectCycles returns cycles among items that only have next steps
+// conditional references are only followed when withConditions is true
+func redirectCycles(items map[string]AppItem, refsById map[string][]ItemRef, withConditions bool) []redirectCycle {
+	isRedirect := func(id string) bool {
+		i, ok := items[id].(item)
+		if !ok {
+			return false
+		}
+		_, ok = i.body.(*nextItem)
+		return ok
+	}
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	cycles := []redirectCycle{}
+	var visit func(id string, path []string, conds []bool)
+	visit = func(id string, path []string, conds []bool) {
+		state[id] = visiting
+		path = append(path, id)
+		for _, ref := range refsById[id] {
+			if !isRedirect(ref.Id) || (ref.Cond != "" && !withConditions) {
+				continue
+			}
+			switch state[ref.Id] {
+			case unvisited:
+				visit(ref.Id, path, append(conds, ref.Cond != ""))
+			case visiting:
+				for index, pathId := range path {
+					if pathId == ref.Id {
+						cycle := redirectCycle{
+							ids:         append(append([]string{}, path[index:]...), ref.Id),
+							conditional: ref.Cond != "",
+						}
+						for _, cond := range conds[index:] {
+							cycle.conditional = cycle.conditional || cond
+						}
+						cycles = append(cycles, cycle)
+						break
+					}
+				}
+			}
+		}
+		state[id] = visited
+	}
+	for _, id := range sortedItemIds(items) {
+		if isRedirect(id) && state[id] == unvisited {
+			visit(id, nil, nil)
+		}
+	}
+	return cycles
+} //redirectCycles()
+
+func sortedItemIds(items map[string]AppItem) []string {
+	ids := make([]string, 0, len(items))
+	for id := range items {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
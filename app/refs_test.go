@@ -0,0 +1,65 @@
+package app
+
+import (
+	"testing"
+)
+
+func TestLoadRefs(t *testing.T) {
+	tests := []struct {
+		name     string
+		items    string
+		mode     DanglingRefs
+		expected []string
+	}{
+		{
+			name:     "dangling",
+			items:    `"home":` + testMenu("missing"),
+			expected: []string{`1 invalid item references`, `app.json:1: item "home" menu.items[0].next[0].item refers to unknown item "missing"`},
+		},
+		{
+			name:     "dangling in else",
+			items:    `"home":{"next":[{"if":{"expr":"X==1", "then":[{"item":"page"}], "else":[{"item":"missing"}]}}]}, "page":` + testMenu("home"),
+			expected: []string{`item "home" next[0].if.else[0].item refers to unknown item "missing"`},
+		},
+		{
+			name:     "all dangling",
+			items:    `"home":` + testMenu("missing") + `, "page":` + testMenu("other"),
+			expected: []string{`2 invalid item references`, `unknown item "missing"`, `unknown item "other"`},
+		},
+		{
+			name:  "dangling warn",
+			items: `"home":` + testMenu("missing"),
+			mode:  DanglingRefsWarn,
+		},
+		{
+			name:  "dangling ignore",
+			items: `"home":` + testMenu("missing"),
+			mode:  DanglingRefsIgnore,
+		},
+		{
+			name:     "redirect cycle",
+			items:    `"home":{"next":[{"item":"a"}]}, "a":{"next":[{"item":"b"}]}, "b":{"next":[{"item":"a"}]}`,
+			expected: []string{`redirect cycle a -> b -> a`},
+		},
+		{
+			name:     "redirect to self",
+			items:    `"home":{"next":[{"item":"home"}]}`,
+			expected: []string{`redirect cycle home -> home`},
+		},
+		{
+			name:  "conditional redirect cycle",
+			items: `"home":{"next":[{"if":{"expr":"X==1", "then":[{"item":"a"}], "else":[{"item":"page"}]}}]}, "a":{"next":[{"item":"home"}]}, "page":` + testMenu("home"),
+		},
+		{
+			name:  "cycle through a page",
+			items: `"home":{"next":[{"item":"a"}]}, "a":{"next":[{"item":"page"}]}, "page":` + testMenu("home"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := testAppDir(t, map[string]string{"app.json": `{` + test.items + `}`})
+			err := New(WithDanglingRefs(test.mode)).Load(dir)
+			testLoadError(t, err, test.expected)
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package app
+
+import (
+	"reflect"
+	"strings"
+)
+
+//go:generate go run ../cmd/goweb1 schema -o ../app.schema.json
+
+// Schema returns a JSON Schema (draft 2020-12) for app files,
+// generated from the item types with their json and doc tags,
+// so that editors can validate and complete app files before Load()
+func Schema() map[string]interface{} {
+	s := schemaBuilder{defs: map[string]interface{}{}}
+	itemRef := s.schemaFor(reflect.TypeOf(item{}))
+	return map[string]interface{}{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"title":       "goweb1 app file",
+		"description": "Items of the app by id. Items in a sub directory are prefixed with the directory path, e.g. \"profile/edit\".",
+		"type":        "object",
+		"propertyNames": map[string]interface{}{
+			"pattern": "^" + itemIdPattern + "$",
+		},
+		"additionalProperties": itemRef,
+		"$defs":                s.defs,
+	}
+} //Schema()
+
+// schemaUnions lists the attributes of which exactly one must be specified
+var schemaUnions = map[reflect.Type][]string{
+	reflect.TypeOf(fileItemNextStep{}): {"item", "set", "if"},
+}
+
+// schemaRequired lists the attributes that Validate() requires
+var schemaRequired = map[reflect.Type][]string{
+	reflect.TypeOf(menu{}):        {"title", "items"},
+	reflect.TypeOf(menuItem{}):    {"caption", "next"},
+	reflect.TypeOf(list{}):        {"title", "get_items", "options"},
+	reflect.TypeOf(ListOptions{}): {"columns"},
+	reflect.TypeOf(ListColumn{}):  {"header", "value"},
+	reflect.TypeOf(edit{}):        {"title", "get_func", "upd_func", "saved_next"},
+	reflect.TypeOf(prompt{}):      {"caption", "name", "next"},
+	reflect.TypeOf(fileItemSet{}): {"name"},
+	reflect.TypeOf(fileItemIf{}):  {"expr", "then", "else"},
+}
+
+// schemaCustom has the schemas of types that parse their own JSON
+var schemaCustom = map[reflect.Type]map[string]interface{}{
+	reflect.TypeOf(ConfiguredTemplate{}): {
+		"type":        "string",
+		"description": "Go template rendered with session data, e.g. \"Hello {{.Name}}\"",
+	},
+	reflect.TypeOf(Actions{}): {
+		"type":        "array",
+		"description": "Actions executed in sequence. Each is {\"<Name>\":<value>} to set a session value or {\"<Name>\":{\"<func>()\":<req>}} to call a registered func and store its result (blank name to discard).",
+		"items": map[string]interface{}{
+			"type":          "object",
+			"minProperties": 1,
+			"maxProperties": 1,
+			"propertyNames": map[string]interface{}{
+				"pattern": "^(" + fieldNamePattern + ")?$",
+			},
+			"additionalProperties": map[string]interface{}{
+				"anyOf": []interface{}{
+					map[string]interface{}{
+						"description":   "Func call with the request value",
+						"type":          "object",
+						"minProperties": 1,
+						"maxProperties": 1,
+						"propertyNames": map[string]interface{}{
+							"pattern": "^[a-zA-Z][a-zA-Z0-9_]*\\(\\)$",
+						},
+					},
+					map[string]interface{}{
+						"description": "Value to set",
+					},
+				},
+			},
+		},
+	},
+	reflect.TypeOf(itemAuth("")): {
+		"type": "string",
+		"enum": []string{string(itemAuthNone), string(itemAuthRequired)},
+	},
+	reflect.TypeOf(fileItemNextItem("")): {
+		"type":        "string",
+		"description": "Next item id, relative to the namespace of the file or absolute when starting with \"/\"",
+		"pattern":     "^/?" + itemIdPattern + "(/" + itemIdPattern + ")*$",
+	},
+	reflect.TypeOf(Caption{}): {
+		"type":        "object",
+		"description": "Text by language code, with \"\" for the default language",
+		"required":    []string{""},
+		"additionalProperties": map[string]interface{}{
+			"$ref": "#/$defs/ConfiguredTemplate",
+		},
+	},
+}
+
+type schemaBuilder struct {
+	defs map[string]interface{}
+}
+
+// schemaFor returns the schema of type t
+// named types from this package are added to defs and referenced
+func (s schemaBuilder) schemaFor(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.PkgPath() != reflect.TypeOf(item{}).PkgPath() || t.Name() == "" {
+		return s.inlineSchemaFor(t)
+	}
+	ref := map[string]interface{}{"$ref": "#/$defs/" + t.Name()}
+	if _, ok := s.defs[t.Name()]; ok {
+		return ref
+	}
+	s.defs[t.Name()] = map[string]interface{}{} //placeholder for recursive types
+	if t == reflect.TypeOf(item{}) {
+		s.defs[t.Name()] = s.itemSchema()
+		return ref
+	}
+	if custom, ok := schemaCustom[t]; ok {
+		s.defs[t.Name()] = custom
+		if t == reflect.TypeOf(Caption{}) {
+			s.schemaFor(reflect.TypeOf(ConfiguredTemplate{}))
+		}
+		return ref
+	}
+	s.defs[t.Name()] = s.inlineSchemaFor(t)
+	return ref
+} //schemaBuilder.schemaFor()
+
+// itemSchema has on_enter_actions, auth, roles and one of the registered item types
+func (s schemaBuilder) itemSchema() map[string]interface{} {
+	properties := map[string]interface{}{}
+	for _, fieldName := range []string{"OnEnter", "Auth", "Roles"} {
+		f, _ := reflect.TypeOf(item{}).FieldByName(fieldName)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		properties[name] = withDoc(s.schemaFor(f.Type), f.Tag.Get("doc"))
+	}
+	oneOf := []interface{}{}
+	for _, name := range itemTypeNames() {
+		body, _ := newItemType(name)
+		properties[name] = s.schemaFor(reflect.TypeOf(body))
+		oneOf = append(oneOf, map[string]interface{}{"required": []string{name}})
+	}
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+		"oneOf":                oneOf,
+	}
+} //schemaBuilder.itemSchema()
+
+// withDoc adds the description to a property schema
+func withDoc(property map[string]interface{}, doc string) map[string]interface{} {
+	if doc == "" {
+		return property
+	}
+	//$ref with siblings is allowed in 2020-12
+	described := map[string]interface{}{"description": doc}
+	for n, v := range property {
+		described[n] = v
+	}
+	return described
+}
+
+func (s schemaBuilder) inlineSchemaFor(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			properties[name] = withDoc(s.schemaFor(f.Type), f.Tag.Get("doc"))
+		}
+		schema := map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+		if required, ok := schemaRequired[t]; ok {
+			schema["required"] = required
+		}
+		if union, ok := schemaUnions[t]; ok {
+			oneOf := []interface{}{}
+			for _, name := range union {
+				oneOf = append(oneOf, map[string]interface{}{"required": []string{name}})
+			}
+			schema["oneOf"] = oneOf
+		}
+		return schema
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": s.schemaFor(t.Elem()),
+		}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": s.schemaFor(t.Elem()),
+		}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	}
+	return map[string]interface{}{} //any value
+} //schemaBuilder.inlineSchemaFor()
@@ -0,0 +1,34 @@
+package app
+
+import "testing"
+
+func TestSchemaRequired(t *testing.T) {
+	s := schemaBuilder{defs: map[string]interface{}{}}
+	for typ, required := range schemaRequired {
+		schema := s.inlineSchemaFor(typ)
+		properties := schema["properties"].(map[string]interface{})
+		for _, name := range required {
+			if _, ok := properties[name]; !ok {
+				t.Errorf("%s required %s is not a property", typ.Name(), name)
+			}
+		}
+	}
+
+	//Load() also rejects items without them
+	tests := []struct {
+		name     string
+		item     string
+		expected []string
+	}{
+		{"menu title", `{"menu":{"items":[{"caption":{"":"Home"}, "next":[{"item":"home"}]}]}}`, []string{`invalid title`}},
+		{"menu items", `{"menu":{"title":{"":"Home"}}}`, []string{`missing items`}},
+		{"prompt name", `{"prompt":{"caption":{"":"Name?"}, "next":[{"item":"home"}]}}`, []string{`invalid name`}},
+		{"if else", `{"menu":{"title":{"":"Home"}, "items":[{"caption":{"":"Home"}, "next":[{"if":{"expr":"1 == 1", "then":[{"item":"home"}]}}]}]}}`, []string{`invalid else`}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := testAppDir(t, map[string]string{"app.json": `{"home":` + test.item + `}`})
+			testLoadError(t, New().Load(dir), test.expected)
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// UnknownAttributes selects what Load does when the app file
+// has JSON attributes that are not used by any item type
+// (e.g. typo "filter" instead of "show_filter" silently disables a feature)
+type UnknownAttributes int
+
+const (
+	UnknownAttributesFail   UnknownAttributes = iota //Load fails (default)
+	UnknownAttributesWarn                            //Load logs each unknown attribute and continues
+	UnknownAttributesIgnore                          //Load ignores unknown attributes
+)
+
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// unknownAttributes returns the JSON path of each attribute in value
+// that does not map to a field in type t
+// value is the generic decoded JSON, i.e. map[string]interface{}, []interface{}, string, ...
+// types that parse their own JSON (e.g. Actions) are not inspected
+func unknownAttributes(t reflect.Type, value interface{}, path string) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(unmarshalerType) {
+		return nil
+	}
+	unknown := []string{}
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil //wrong type is reported when decoding
+		}
+		for _, name := range sortedKeys(obj) {
+			field, ok := jsonField(t, name)
+			if !ok {
+				unknown = append(unknown, attrPath(path, name))
+				continue
+			}
+			unknown = append(unknown, unknownAttributes(field.Type, obj[name], attrPath(path, name))...)
+		}
+	case reflect.Map:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for _, name := range sortedKeys(obj) {
+			unknown = append(unknown, unknownAttributes(t.Elem(), obj[name], fmt.Sprintf("%s[%q]", path, name))...)
+		}
+	case reflect.Slice, reflect.Array:
+		list, ok := value.([]interface{})
+		if !ok {
+			return nil
+		}
+		for index, elem := range list {
+			unknown = append(unknown, unknownAttributes(t.Elem(), elem, fmt.Sprintf("%s[%d]", path, index))...)
+		}
+	}
+	return unknown
+} //unknownAttributes()
+
+// unknownAttributes returns the JSON path of each attribute in value not used by the item
+// the item is already parsed, so its type is known
+func (i item) unknownAttributes(value interface{}, path string) []string {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	unknown := []string{}
+	for _, name := range sortedKeys(obj) {
+		switch name {
+		case "on_enter_actions", "auth", "roles":
+			//parsed by item.UnmarshalJSON()
+		case i.kind:
+			unknown = append(unknown, unknownAttributes(reflect.TypeOf(i.body), obj[name], attrPath(path, name))...)
+		default:
+			unknown = append(unknown, attrPath(path, name))
+		}
+	}
+	return unknown
+} //item.unknownAttributes()
+
+// jsonField finds the struct field that encoding/json will decode the named attribute into
+// (json matches names case insensitive, so do the same here)
+func jsonField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tagName, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if tagName == "-" {
+			continue
+		}
+		if tagName == "" {
+			tagName = f.Name
+		}
+		if strings.EqualFold(tagName, name) {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func attrPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedRawKeys(obj map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"testing"
+)
+
+func TestLoadUnknownAttributes(t *testing.T) {
+	tests := []struct {
+		name     string
+		item     string
+		mode     UnknownAttributes
+		expected []string
+	}{
+		{
+			name:     "type attribute",
+			item:     `{"menu":{"titel":{"":"Test"}, "title":{"":"Test"}, "items":[{"caption":{"":"Next"}, "next":[{"item":"home"}]}]}}`,
+			expected: []string{`item "home" has unknown attributes: home.menu.titel`},
+		},
+		{
+			name:     "nested attribute",
+			item:     `{"menu":{"title":{"":"Test"}, "items":[{"caption":{"":"Next"}, "nxt":[], "next":[{"item":"home", "itme":"x"}]}]}}`,
+			expected: []string{`home.menu.items[0].next[0].itme`, `home.menu.items[0].nxt`},
+		},
+		{
+			name:     "item attribute",
+			item:     `{"on_enter":[], "menu":{"title":{"":"Test"}, "items":[{"caption":{"":"Next"}, "next":[{"item":"home"}]}]}}`,
+			expected: []string{`item "home" has unknown attributes: home.on_enter`},
+		},
+		{
+			name:     "unknown type",
+			item:     `{"menus":{"title":{"":"Test"}}}`,
+			expected: []string{`item "home" has unknown attributes: home.menus`},
+		},
+		{
+			name:     "unknown type with warn",
+			item:     `{"menus":{"title":{"":"Test"}}}`,
+			mode:     UnknownAttributesWarn,
+			expected: []string{`invalid item "home"`, `missing edit|`},
+		},
+		{
+			name: "warn",
+			item: `{"menu":{"titel":{"":"Test"}, "title":{"":"Test"}, "items":[{"caption":{"":"Next"}, "next":[{"item":"home"}]}]}}`,
+			mode: UnknownAttributesWarn,
+		},
+		{
+			name: "ignore",
+			item: `{"menu":{"titel":{"":"Test"}, "title":{"":"Test"}, "items":[{"caption":{"":"Next"}, "next":[{"item":"home"}]}]}}`,
+			mode: UnknownAttributesIgnore,
+		},
+		{
+			name: "actions are not inspected",
+			item: `{"on_enter_actions":[{"X":{"set":"1"}}], "menu":{"title":{"":"Test"}, "items":[{"caption":{"":"Next"}, "next":[{"item":"home"}]}]}}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := testAppDir(t, map[string]string{"app.json": `{"home":` + test.item + `}`})
+			err := New(WithUnknownAttributes(test.mode)).Load(dir)
+			testLoadError(t, err, test.expected)
+		})
+	}
+}
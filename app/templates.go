@@ -1,25 +1,179 @@
 package app
 
 import (
+	"context"
+	"embed"
 	"html/template"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"sync"
 
 	"github.com/go-msvc/errors"
 )
 
-func LoadPageTemplates(templateNames []string) (*template.Template, error) {
-	templateNames = append(templateNames, "page")
-	return LoadTemplates(templateNames)
+// defaultTemplates are used for all templates not found in the app's template overlays
+//
+//go:embed templates/*.tmpl
+var defaultTemplates embed.FS
+
+// CtxApp is the App in the context passed to items, e.g. to render pages
+type CtxApp struct{}
+
+// CtxStylesheets are the URLs ([]string) of stylesheets to link in pages
+type CtxStylesheets struct{}
+
+// CtxLoginEnabled (bool) is true when the web server serves /login and /register
+type CtxLoginEnabled struct{}
+
+// CtxCSRFToken is the token (string) that forms must post as CSRFFieldName,
+// set by the web server unless it does not check it
+type CtxCSRFToken struct{}
+
+// CtxFormError is the message (string) of why the posted form was not accepted,
+// shown above the form when it is rendered again
+type CtxFormError struct{}
+
+// CtxFormValues are the values (url.Values) of the posted form that was not accepted,
+// items fill them into the form when it is rendered again, so the user can correct them
+type CtxFormValues struct{}
+
+// CtxPageToken is the token (string) of the page being rendered, set by the web server
+// forms post it as PageTokenFieldName, so that only the form of the current page is processed
+type CtxPageToken struct{}
+
+// hidden fields in forms, templates with forms must include them
+// with {{template "form_tokens" .FormTokens}} (defined in page.tmpl)
+const (
+	CSRFFieldName      = "csrf_token"
+	PageTokenFieldName = "page_token"
+)
+
+// FormTokens are the hidden fields of forms, blank ones are not rendered
+type FormTokens struct {
+	CSRF string
+	Page string
 }
 
-func LoadTemplates(templateNames []string) (*template.Template, error) {
-	templateFileNames := []string{}
-	for _, n := range templateNames {
-		templateFileNames = append(templateFileNames, "./templates/"+n+".tmpl")
+// formValue returns the value posted for the named field when the form is rendered again
+func formValue(ctx context.Context, name string) (string, bool) {
+	values, _ := ctx.Value(CtxFormValues{}).(url.Values)
+	if _, ok := values[name]; !ok {
+		return "", false
 	}
-	t, err := template.ParseFiles(templateFileNames...)
+	return values.Get(name), true
+}
+
+// formTokens returns the tokens to put in forms of the page rendered in ctx
+func formTokens(ctx context.Context) FormTokens {
+	tokens := FormTokens{}
+	tokens.CSRF, _ = ctx.Value(CtxCSRFToken{}).(string)
+	tokens.Page, _ = ctx.Value(CtxPageToken{}).(string)
+	return tokens
+}
+
+// WithTemplateDir overlays the default templates with <name>.tmpl files in dir
+// only templates in the dir are replaced, e.g. only "page.tmpl" to change the layout
+func WithTemplateDir(dir string) Option {
+	return WithTemplateFS(os.DirFS(dir))
+}
+
+// WithTemplateFS overlays the default templates with <name>.tmpl files in fsys
+// when used more than once, the last overlay with a template is used
+func WithTemplateFS(fsys fs.FS) Option {
+	return func(app *app) {
+		app.templates.overlays = append([]fs.FS{fsys}, app.templates.overlays...)
+	}
+}
+
+// RenderPage executes the named template (e.g. "menu") inside the page template
+func (app *app) RenderPage(buffer io.Writer, templateName string, data TmplData) error {
+	tmpl, err := app.templates.page(templateName)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to load template files: %v", templateFileNames)
+		return errors.Wrapf(err, "failed to load %s template", templateName)
+	}
+	return tmpl.ExecuteTemplate(buffer, "page", data)
+} //app.RenderPage()
+
+// renderPage is used by items to render with the app in the context
+func renderPage(ctx context.Context, buffer io.Writer, templateName string, data TmplData) error {
+	app, ok := ctx.Value(CtxApp{}).(App)
+	if !ok {
+		return errors.Errorf("missing app in context")
 	}
-	log.Debugf("loaded %v", templateFileNames)
-	return t, nil
+	return app.RenderPage(buffer, templateName, data)
+}
+
+// PageTmplData is the data for the page template with the body of the item,
+// e.g. for item types or error pages to render with App.RenderPage()
+func PageTmplData(ctx context.Context, body interface{}) TmplData {
+	stylesheets, _ := ctx.Value(CtxStylesheets{}).([]string)
+	navBar := TmplNavBar{}
+	if user := UserFrom(ctx); user != nil {
+		navBar.Email = user.Email
+		navBar.Phone = user.Phone
+		navBar.Name = user.Name
+	}
+	navBar.LoginEnabled, _ = ctx.Value(CtxLoginEnabled{}).(bool)
+	navBar.FormTokens.CSRF, _ = ctx.Value(CtxCSRFToken{}).(string)
+	formError, _ := ctx.Value(CtxFormError{}).(string)
+	return TmplData{
+		NavBar:      navBar,
+		Stylesheets: stylesheets,
+		FormError:   formError,
+		Body:        body,
+	}
+}
+
+// templates are loaded when first used, so that nothing is read
+// when only loading the app, e.g. in tools
+type templates struct {
+	overlays []fs.FS //first has precedence, then the next, then defaultTemplates
+	mutex    sync.Mutex
+	pages    map[string]*template.Template
 }
+
+// page returns the named template combined with the page template
+func (t *templates) page(name string) (*template.Template, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if tmpl, ok := t.pages[name]; ok {
+		return tmpl, nil
+	}
+	tmpl := template.New(name)
+	for _, n := range []string{name, "page"} {
+		text, source, err := t.read(n)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tmpl.New(n + ".tmpl").Parse(text); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %s from %s", n, source)
+		}
+		log.Debugf("loaded template %s from %s", n, source)
+	}
+	if t.pages == nil {
+		t.pages = map[string]*template.Template{}
+	}
+	t.pages[name] = tmpl
+	return tmpl, nil
+} //templates.page()
+
+// read returns the text of the named template from the first overlay that has it
+func (t *templates) read(name string) (text string, source string, err error) {
+	filename := name + ".tmpl"
+	for _, overlay := range t.overlays {
+		data, err := fs.ReadFile(overlay, filename)
+		if err == nil {
+			return string(data), "overlay " + filename, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", "", errors.Wrapf(err, "failed to read %s", filename)
+		}
+	}
+	data, err := defaultTemplates.ReadFile("templates/" + filename)
+	if err != nil {
+		return "", "", errors.Errorf("unknown template \"%s\"", name)
+	}
+	return string(data), "default " + filename, nil
+} //templates.read()
@@ -0,0 +1,110 @@
+package app
+
+import (
+	"bytes"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+var testErrorBody = map[string]interface{}{
+	"Title":   "Test Title",
+	"Message": "test message",
+	"Link":    "/?next=home",
+	"Button":  "Home",
+}
+
+func TestDefaultTemplates(t *testing.T) {
+	filenames, err := fs.Glob(defaultTemplates, "templates/*.tmpl")
+	if err != nil {
+		t.Fatalf("failed to list default templates: %+v", err)
+	}
+	if len(filenames) == 0 {
+		t.Fatalf("no default templates embedded")
+	}
+	a := New().(*app)
+	for _, filename := range filenames {
+		name := strings.TrimSuffix(strings.TrimPrefix(filename, "templates/"), ".tmpl")
+		if name == "page" {
+			continue //parsed with each of the others
+		}
+		tmpl, err := a.templates.page(name)
+		if err != nil {
+			t.Errorf("failed to load %s: %+v", name, err)
+			continue
+		}
+		for _, defined := range []string{"page", "head", "body", "navbar", "form_tokens"} {
+			if tmpl.Lookup(defined) == nil {
+				t.Errorf("%s does not define \"%s\"", name, defined)
+			}
+		}
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	data := TmplData{NavBar: TmplNavBar{Email: "test@example.com"}, Body: testErrorBody}
+	if err := a.RenderPage(buffer, "error", data); err != nil {
+		t.Fatalf("failed to render error page: %+v", err)
+	}
+	for _, expected := range []string{"<!DOCTYPE html>", "<title>Test Title</title>", "test message", "test@example.com"} {
+		if !strings.Contains(buffer.String(), expected) {
+			t.Errorf("error page does not contain %q:\n%s", expected, buffer.String())
+		}
+	}
+}
+
+func TestTemplateOverlays(t *testing.T) {
+	pageOverlay := fstest.MapFS{
+		"page.tmpl": {Data: []byte(`{{define "page"}}custom page [{{template "body" .Body}}]{{end}}`)},
+	}
+	errorOverlay := fstest.MapFS{
+		"error.tmpl": {Data: []byte(`{{define "head"}}{{end}}{{define "body"}}first {{.Title}}{{end}}`)},
+	}
+	laterErrorOverlay := fstest.MapFS{
+		"error.tmpl": {Data: []byte(`{{define "head"}}{{end}}{{define "body"}}last {{.Title}}{{end}}`)},
+	}
+	tests := []struct {
+		name     string
+		options  []Option
+		expected []string
+	}{
+		{"page", []Option{WithTemplateFS(pageOverlay)}, []string{"custom page [", "<h1>Test Title</h1>", "]"}},
+		{"body", []Option{WithTemplateFS(errorOverlay)}, []string{"<!DOCTYPE html>", "first Test Title"}},
+		{"last overlay first", []Option{WithTemplateFS(errorOverlay), WithTemplateFS(laterErrorOverlay)}, []string{"last Test Title"}},
+		{"both", []Option{WithTemplateFS(pageOverlay), WithTemplateFS(errorOverlay)}, []string{"custom page [first Test Title]"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buffer := bytes.NewBuffer(nil)
+			if err := New(test.options...).RenderPage(buffer, "error", TmplData{Body: testErrorBody}); err != nil {
+				t.Fatalf("failed to render: %+v", err)
+			}
+			for _, expected := range test.expected {
+				if !strings.Contains(buffer.String(), expected) {
+					t.Errorf("page does not contain %q:\n%s", expected, buffer.String())
+				}
+			}
+		})
+	}
+
+	_, err := New(WithTemplateFS(fstest.MapFS{})).(*app).templates.page("unknown")
+	if err == nil {
+		t.Errorf("loaded unknown template")
+	}
+}
+
+func TestNavBarLogin(t *testing.T) {
+	a := New()
+	for _, loginEnabled := range []bool{false, true} {
+		buffer := bytes.NewBuffer(nil)
+		data := TmplData{NavBar: TmplNavBar{LoginEnabled: loginEnabled}, Body: testErrorBody}
+		if err := a.RenderPage(buffer, "error", data); err != nil {
+			t.Fatalf("failed to render error page: %+v", err)
+		}
+		for _, link := range []string{`href="/login"`, `href="/register"`} {
+			if strings.Contains(buffer.String(), link) != loginEnabled {
+				t.Errorf("LoginEnabled=%v but link %s is %v:\n%s", loginEnabled, link, !loginEnabled, buffer.String())
+			}
+		}
+	}
+}
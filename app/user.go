@@ -0,0 +1,32 @@
+package app
+
+import "context"
+
+// User is the authenticated user of a session, set by the web server after login
+type User struct {
+	ID    string
+	Email string
+	Phone string //verified phone number with AUTH=otp
+	Name  string
+	Roles []string
+}
+
+// CtxUser is the *User in the context of a request when the session has an authenticated user
+type CtxUser struct{}
+
+// HasRole is true when the user has the role
+func (user User) HasRole(role string) bool {
+	for _, r := range user.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// UserFrom returns the authenticated user in the context of a request, or nil when not logged in
+// funcs can also take the user as argument (see RegisterFunc())
+func UserFrom(ctx context.Context) *User {
+	user, _ := ctx.Value(CtxUser{}).(*User)
+	return user
+}
@@ -0,0 +1,73 @@
+package app
+
+import (
+	"sort"
+	"time"
+)
+
+// Version returns the current version, which new sessions must use
+func (app *app) Version() string {
+	return app.definition().version
+}
+
+// Versions returns all versions that are still available
+func (app *app) Versions() []string {
+	app.retireVersions()
+	app.mutex.RLock()
+	defer app.mutex.RUnlock()
+	versions := make([]string, 0, len(app.versions))
+	for version := range app.versions {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+func (app *app) definition() *definition {
+	app.mutex.RLock()
+	defer app.mutex.RUnlock()
+	return app.current
+}
+
+// versionDefinition returns the specified version, or the current version if ""
+// it fails if the version is unknown or was retired
+func (app *app) versionDefinition(version string) (*definition, bool) {
+	if version == "" {
+		return app.definition(), true
+	}
+	app.retireVersions()
+	app.mutex.RLock()
+	defer app.mutex.RUnlock()
+	def, ok := app.versions[version]
+	return def, ok
+}
+
+// setDefinition makes def the current version
+// and starts the grace period of the previous version
+func (app *app) setDefinition(def *definition) {
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+	previous := app.current
+	if previous.version != "" && previous.version != def.version {
+		if app.retireAfter > 0 {
+			previous.retireAt = time.Now().Add(app.retireAfter)
+		}
+		log.Infof("App version %s replaced by %s (retire at %v)", previous.version, def.version, previous.retireAt)
+	}
+	def.retireAt = time.Time{}
+	app.current = def
+	app.versions[def.version] = def
+}
+
+// retireVersions removes older versions after their grace period
+func (app *app) retireVersions() {
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+	now := time.Now()
+	for version, def := range app.versions {
+		if def != app.current && !def.retireAt.IsZero() && now.After(def.retireAt) {
+			delete(app.versions, version)
+			log.Infof("App version %s retired", version)
+		}
+	}
+}
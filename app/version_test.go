@@ -0,0 +1,103 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestVersions(t *testing.T) {
+	retireAfter := 200 * time.Millisecond
+	dir := testAppDir(t, map[string]string{
+		"v1/app.json": `{"home":` + testMenu("home") + `}`,
+		"v2/app.json": `{"home":` + testMenu("page") + `, "page":` + testMenu("home") + `}`,
+	})
+	a := New(WithRetireAfter(retireAfter))
+	if err := a.Load(filepath.Join(dir, "v1")); err != nil {
+		t.Fatalf("failed to load: %+v", err)
+	}
+	v1 := a.Version()
+	if len(v1) != 12 {
+		t.Fatalf("version \"%s\" is not a hash", v1)
+	}
+
+	//sessions on v1 continue with its items after v2 is loaded
+	if err := a.LoadVersion("v2", filepath.Join(dir, "v2")); err != nil {
+		t.Fatalf("failed to load v2: %+v", err)
+	}
+	if a.Version() != "v2" || !reflect.DeepEqual(a.Versions(), []string{v1, "v2"}) {
+		t.Fatalf("version %s of %v after loading v2", a.Version(), a.Versions())
+	}
+	if _, ok := a.GetItem(v1, "page"); ok {
+		t.Errorf("v1 has v2 item")
+	}
+	if _, ok := a.GetItem(v1, "home"); !ok {
+		t.Errorf("v1 item not found")
+	}
+	if _, ok := a.GetItem("", "page"); !ok {
+		t.Errorf("v2 item not found in current version")
+	}
+	if _, ok := a.GetItem("v3", "home"); ok {
+		t.Errorf("found item in unknown version")
+	}
+	if err := a.LoadVersion("", filepath.Join(dir, "v2")); err == nil {
+		t.Errorf("loaded version without a name")
+	}
+
+	//a named version keeps its name when reloaded
+	if err := a.Reload(); err != nil {
+		t.Fatalf("failed to reload unchanged: %+v", err)
+	}
+	if a.Version() != "v2" {
+		t.Fatalf("version %s after reload unchanged", a.Version())
+	}
+	if err := os.WriteFile(filepath.Join(dir, "v2", "app.json"), []byte(`{"home":`+testMenu("other")+`, "other":`+testMenu("home")+`}`), 0644); err != nil {
+		t.Fatalf("failed to write: %+v", err)
+	}
+	if err := a.Reload(); err != nil {
+		t.Fatalf("failed to reload: %+v", err)
+	}
+	if _, ok := a.GetItem("v2", "other"); a.Version() != "v2" || !ok {
+		t.Fatalf("version %s after reload changed (other found: %v)", a.Version(), ok)
+	}
+
+	//an invalid change is not loaded
+	if err := os.WriteFile(filepath.Join(dir, "v2", "app.json"), []byte(`{"home":`+testMenu("missing")+`}`), 0644); err != nil {
+		t.Fatalf("failed to write: %+v", err)
+	}
+	if err := a.Reload(); err == nil {
+		t.Fatalf("reloaded invalid app")
+	}
+	if _, ok := a.GetItem("", "other"); !ok {
+		t.Fatalf("current version replaced by invalid reload")
+	}
+
+	//v1 is retired after WithRetireAfter()
+	time.Sleep(retireAfter + 10*time.Millisecond)
+	if !reflect.DeepEqual(a.Versions(), []string{"v2"}) {
+		t.Fatalf("versions %v after retire", a.Versions())
+	}
+	if _, ok := a.GetItem(v1, "home"); ok {
+		t.Errorf("found item in retired version")
+	}
+}
+
+func TestVersionsNotRetired(t *testing.T) {
+	dir := testAppDir(t, map[string]string{
+		"v1/app.json": `{"home":` + testMenu("home") + `}`,
+		"v2/app.json": `{"home":` + testMenu("page") + `, "page":` + testMenu("home") + `}`,
+	})
+	a := New(WithRetireAfter(0))
+	if err := a.LoadVersion("v1", filepath.Join(dir, "v1")); err != nil {
+		t.Fatalf("failed to load v1: %+v", err)
+	}
+	if err := a.LoadVersion("v2", filepath.Join(dir, "v2")); err != nil {
+		t.Fatalf("failed to load v2: %+v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if !reflect.DeepEqual(a.Versions(), []string{"v1", "v2"}) {
+		t.Fatalf("versions %v with WithRetireAfter(0)", a.Versions())
+	}
+}
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-msvc/errors"
+)
+
+// Watch checks the loaded files every interval and reloads the app when
+// any file changed, was added or removed
+// it runs in the background until the context is done
+// when the changed files are not valid, the error is logged and the
+// current items remain in use until the files are fixed
+// it returns an error when interval is not positive
+func (app *app) Watch(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return errors.Errorf("invalid watch interval %v, expecting > 0", interval)
+	}
+	go func() {
+		lastFingerprint := app.fingerprint()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		log.Debugf("Watching app files every %v", interval)
+		for {
+			select {
+			case <-ctx.Done():
+				log.Debugf("Stopped watching app files")
+				return
+			case <-ticker.C:
+			}
+			fingerprint := app.fingerprint()
+			if fingerprint == lastFingerprint {
+				continue
+			}
+			//only try once for each change, not on every tick
+			lastFingerprint = fingerprint
+			if err := app.Reload(); err != nil {
+				log.Errorf("App files changed but not reloaded: %+v", err)
+				continue
+			}
+			log.Infof("Reloaded app files (%d items)", len(app.ItemIds()))
+		}
+	}()
+	return nil
+} //app.Watch()
+
+// fingerprint describes the name, size and modification time of all loaded files
+// so that changes can be detected without reading them
+func (app *app) fingerprint() string {
+	files, err := findAppFiles(app.definition().patterns)
+	if err != nil {
+		return fmt.Sprintf("error: %s", err.Error())
+	}
+	fingerprint := strings.Builder{}
+	for _, file := range files {
+		info, err := os.Stat(file.filename)
+		if err != nil {
+			fmt.Fprintf(&fingerprint, "%s:error;", file.filename)
+			continue
+		}
+		fmt.Fprintf(&fingerprint, "%s:%d:%d;", file.filename, info.Size(), info.ModTime().UnixNano())
+	}
+	return fingerprint.String()
+} //app.fingerprint()
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/go-msvc/errors"
+)
+
+func describe(args []string) error {
+	flags := newAppFlags("describe")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 1 {
+		return errors.Errorf("missing item id")
+	}
+	id := flags.Arg(0)
+	a, err := flags.load(flags.Args()[1:])
+	if err != nil {
+		return err
+	}
+	info, ok := a.ItemInfo(id)
+	if !ok {
+		return errors.Errorf("unknown item \"%s\"", id)
+	}
+
+	fmt.Printf("item:   %s\n", info.Id)
+	fmt.Printf("type:   %s\n", info.Type)
+	fmt.Printf("source: %s\n", info.Source)
+	if len(info.Access.Roles) > 0 {
+		fmt.Printf("auth:   required, roles %s\n", strings.Join(info.Access.Roles, "|"))
+	} else if info.Access.AuthRequired {
+		fmt.Printf("auth:   required\n")
+	}
+	if len(info.Actions) > 0 {
+		fmt.Printf("on_enter_actions:\n")
+		for _, action := range info.Actions {
+			fmt.Printf("  %s\n", action)
+		}
+	}
+	if len(info.Funcs) > 0 {
+		fmt.Printf("funcs:\n")
+		for _, name := range info.Funcs {
+			fmt.Printf("  %s()\n", name)
+		}
+	}
+	if len(info.Refs) > 0 {
+		fmt.Printf("next:\n")
+		for _, ref := range info.Refs {
+			if label := refLabel(ref); label != "" {
+				fmt.Printf("  %s -> %s (%s)\n", ref.Path, ref.Id, label)
+			} else {
+				fmt.Printf("  %s -> %s\n", ref.Path, ref.Id)
+			}
+		}
+	}
+
+	//indent without escaping html in templates, e.g. "<" or "&"
+	definition := bytes.NewBuffer(nil)
+	encoder := json.NewEncoder(definition)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(info.Definition); err != nil {
+		return errors.Wrapf(err, "failed to encode item definition")
+	}
+	fmt.Printf("definition:\n%s", definition.String())
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/go-msvc/errors"
+	"github.com/jansemmelink/goweb1/app"
+)
+
+// funcs are declared one per line in the form "name(<req type>) <res type>",
+// where context and error are implied and req/res types are optional,
+// funcs that take the logged in user start with *app.User, e.g.:
+//
+//	# comment
+//	getProfile(*app.User) Profile
+//	updProfile(*app.User, Profile)
+//	listOfJobs() app.ColumnList
+//
+// the types are only documentation: the tool registers a stub func with the
+// same number of args and results which fails if it is called
+var funcSignatureRegex = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9_]*)\s*\(([^)]*)\)\s*(.*)$`)
+
+var (
+	contextType   = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType     = reflect.TypeOf((*error)(nil)).Elem()
+	interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+	userType      = reflect.TypeOf((*app.User)(nil))
+)
+
+func registerFuncs(a app.App, filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open file")
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	lineNr := 0
+	for scanner.Scan() {
+		lineNr++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := funcSignatureRegex.FindStringSubmatch(line)
+		if parts == nil {
+			return errors.Errorf("%s:%d: invalid func signature \"%s\" (expect \"name(<req type>) <res type>\")", filename, lineNr, line)
+		}
+		name, req, res := parts[1], strings.TrimSpace(parts[2]), strings.TrimSpace(parts[3])
+		hasUser := false
+		if first, rest, _ := strings.Cut(req, ","); strings.TrimSpace(first) == "*app.User" {
+			hasUser = true
+			req = strings.TrimSpace(rest)
+		}
+		if strings.Contains(req, ",") {
+			return errors.Errorf("%s:%d: func %s() takes more than one argument besides *app.User", filename, lineNr, name)
+		}
+		if err := a.RegisterFunc(name, stubFunc(name, hasUser, req != "", res != "")); err != nil {
+			return errors.Wrapf(err, "%s:%d: cannot register func", filename, lineNr)
+		}
+	}
+	return scanner.Err()
+} //registerFuncs()
+
+// stubFunc makes a func with the same shape as the declared func
+func stubFunc(name string, hasUser, hasReq, hasRes bool) interface{} {
+	in := []reflect.Type{contextType}
+	if hasUser {
+		in = append(in, userType)
+	}
+	if hasReq {
+		in = append(in, interfaceType)
+	}
+	out := []reflect.Type{errorType}
+	if hasRes {
+		out = []reflect.Type{interfaceType, errorType}
+	}
+	funcType := reflect.FuncOf(in, out, false)
+	return reflect.MakeFunc(funcType, func(args []reflect.Value) []reflect.Value {
+		results := []reflect.Value{}
+		if hasRes {
+			results = append(results, reflect.Zero(interfaceType))
+		}
+		err := errors.Errorf("%s() is only declared, not implemented", name)
+		return append(results, reflect.ValueOf(&err).Elem())
+	}).Interface()
+} //stubFunc()
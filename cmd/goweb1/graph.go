@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/go-msvc/errors"
+	"github.com/jansemmelink/goweb1/app"
+)
+
+func graph(args []string) error {
+	flags := newAppFlags("graph")
+	format := flags.String("format", "dot", "output format: dot|mermaid")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	a, err := flags.load(flags.Args())
+	if err != nil {
+		return err
+	}
+	switch *format {
+	case "dot":
+		return writeDot(os.Stdout, a)
+	case "mermaid":
+		return writeMermaid(os.Stdout, a)
+	}
+	return errors.Errorf("unknown format \"%s\" (expect dot|mermaid)", *format)
+}
+
+// writeDot writes a Graphviz digraph with a node for each item
+// and an edge for each reference to a next item
+func writeDot(w io.Writer, a app.App) error {
+	dotShapes := map[string]string{
+		"menu":   "box",
+		"prompt": "parallelogram",
+		"list":   "box3d",
+		"edit":   "note",
+		"next":   "diamond",
+	}
+	fmt.Fprintf(w, "digraph app {\n")
+	fmt.Fprintf(w, "\trankdir=LR;\n")
+	for _, id := range a.ItemIds() {
+		info, _ := a.ItemInfo(id)
+		shape, ok := dotShapes[info.Type]
+		if !ok {
+			shape = "ellipse"
+		}
+		fmt.Fprintf(w, "\t%q [shape=%s];\n", id, shape)
+	}
+	missing := map[string]bool{}
+	for _, id := range a.ItemIds() {
+		info, _ := a.ItemInfo(id)
+		for _, ref := range info.Refs {
+			//refs to unknown items (loaded with -warn) are dashed nodes
+			if _, ok := a.ItemInfo(ref.Id); !ok && !missing[ref.Id] {
+				missing[ref.Id] = true
+				fmt.Fprintf(w, "\t%q [style=dashed,label=%q];\n", ref.Id, ref.Id+" (missing)")
+			}
+			if label := refLabel(ref); label != "" {
+				fmt.Fprintf(w, "\t%q -> %q [label=%q];\n", id, ref.Id, label)
+			} else {
+				fmt.Fprintf(w, "\t%q -> %q;\n", id, ref.Id)
+			}
+		}
+	}
+	fmt.Fprintf(w, "}\n")
+	return nil
+} //writeDot()
+
+// writeMermaid writes a Mermaid flowchart with the same nodes and edges as writeDot
+// item ids are not valid Mermaid node ids, so nodes are numbered
+func writeMermaid(w io.Writer, a app.App) error {
+	mermaidShapes := map[string]string{
+		"menu":   "[\"%s\"]",
+		"prompt": "[/\"%s\"/]",
+		"list":   "[[\"%s\"]]",
+		"edit":   "[(\"%s\")]",
+		"next":   "{\"%s\"}",
+	}
+	nodeIds := map[string]string{}
+	fmt.Fprintf(w, "flowchart LR\n")
+	for index, id := range a.ItemIds() {
+		info, _ := a.ItemInfo(id)
+		nodeIds[id] = fmt.Sprintf("n%d", index)
+		shape, ok := mermaidShapes[info.Type]
+		if !ok {
+			shape = "(\"%s\")"
+		}
+		fmt.Fprintf(w, "\t%s"+shape+"\n", nodeIds[id], mermaidText(id))
+	}
+	for _, id := range a.ItemIds() {
+		info, _ := a.ItemInfo(id)
+		for _, ref := range info.Refs {
+			//refs to unknown items (loaded with -warn) get a node when first referenced
+			if _, ok := nodeIds[ref.Id]; !ok {
+				nodeIds[ref.Id] = fmt.Sprintf("missing%d", len(nodeIds))
+				fmt.Fprintf(w, "\t%s[\"%s (missing)\"]\n", nodeIds[ref.Id], mermaidText(ref.Id))
+			}
+			if label := refLabel(ref); label != "" {
+				fmt.Fprintf(w, "\t%s -->|\"%s\"| %s\n", nodeIds[id], mermaidText(label), nodeIds[ref.Id])
+			} else {
+				fmt.Fprintf(w, "\t%s --> %s\n", nodeIds[id], nodeIds[ref.Id])
+			}
+		}
+	}
+	return nil
+} //writeMermaid()
+
+// refLabel describes how the user gets to the next item:
+// the caption of the menu item/operation and the if/else condition, if any
+func refLabel(ref app.ItemRef) string {
+	switch {
+	case ref.Label != "" && ref.Cond != "":
+		return ref.Label + " [" + ref.Cond + "]"
+	case ref.Cond != "":
+		return "[" + ref.Cond + "]"
+	}
+	return ref.Label
+}
+
+func mermaidText(s string) string {
+	return strings.ReplaceAll(s, "\"", "#quot;")
+}
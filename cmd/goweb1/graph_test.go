@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// testDanglingApp refers to "add-job" which is not defined
+const testDanglingApp = `{
+	"home":{
+		"menu":{
+			"title":{"":"Home"},
+			"items":[
+				{"caption":{"":"Jobs"}, "next":[{"item":"jobs"}]},
+				{"caption":{"":"Add Job"}, "next":[{"item":"add-job"}]}
+			]
+		}
+	},
+	"jobs":{
+		"menu":{
+			"title":{"":"Jobs"},
+			"items":[{"caption":{"":"New Job"}, "next":[{"item":"add-job"}]}]
+		}
+	}
+}`
+
+var (
+	mermaidNodeRegex = regexp.MustCompile(`^\t(\w+)[\[\(\{/]`)
+	mermaidEdgeRegex = regexp.MustCompile(`^\t(\w+) -->(\|"[^"]*"\|)? (\w+)$`)
+)
+
+func TestGraphDanglingRef(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.json")
+	if err := os.WriteFile(filename, []byte(testDanglingApp), 0644); err != nil {
+		t.Fatalf("failed to write app file: %+v", err)
+	}
+	flags := newAppFlags("graph")
+	if err := flags.Parse([]string{"-warn", filename}); err != nil {
+		t.Fatalf("failed to parse flags: %+v", err)
+	}
+	a, err := flags.load(flags.Args())
+	if err != nil {
+		t.Fatalf("failed to load with -warn: %+v", err)
+	}
+
+	t.Run("mermaid", func(t *testing.T) {
+		buffer := bytes.NewBuffer(nil)
+		if err := writeMermaid(buffer, a); err != nil {
+			t.Fatalf("failed to write: %+v", err)
+		}
+		nodes := map[string]bool{}
+		edges := 0
+		for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n")[1:] {
+			if match := mermaidEdgeRegex.FindStringSubmatch(line); match != nil {
+				if !nodes[match[1]] || !nodes[match[3]] {
+					t.Errorf("edge to undefined node: %q", line)
+				}
+				edges++
+				continue
+			}
+			if match := mermaidNodeRegex.FindStringSubmatch(line); match != nil {
+				nodes[match[1]] = true
+				continue
+			}
+			t.Errorf("invalid line: %q", line)
+		}
+		if edges != 3 {
+			t.Errorf("%d edges instead of 3:\n%s", edges, buffer.String())
+		}
+		if strings.Count(buffer.String(), `["add-job (missing)"]`) != 1 {
+			t.Errorf("expected one missing node:\n%s", buffer.String())
+		}
+	})
+
+	t.Run("dot", func(t *testing.T) {
+		buffer := bytes.NewBuffer(nil)
+		if err := writeDot(buffer, a); err != nil {
+			t.Fatalf("failed to write: %+v", err)
+		}
+		for _, expected := range []string{
+			`"add-job" [style=dashed,label="add-job (missing)"];`,
+			`"home" -> "add-job" [label="Add Job"];`,
+			`"jobs" -> "add-job" [label="New Job"];`,
+		} {
+			if !strings.Contains(buffer.String(), expected) {
+				t.Errorf("graph does not contain %q:\n%s", expected, buffer.String())
+			}
+		}
+	})
+}
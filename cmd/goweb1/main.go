@@ -0,0 +1,143 @@
+// goweb1 checks and documents app definitions without running the web server
+//
+// Usage:
+//
+//	goweb1 lint     [-funcs <file>] [-warn] <app files...>
+//	goweb1 graph    [-funcs <file>] [-warn] [-format dot|mermaid] <app files...>
+//	goweb1 describe [-funcs <file>] [-warn] <item id> <app files...>
+//	goweb1 schema   [-o <file>]
+//
+// App files may be files, directories or glob patterns as accepted by app.Load().
+// The funcs file lists the funcs that the app registers in code, see funcs.go.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/go-msvc/errors"
+	"github.com/go-msvc/logger"
+	"github.com/jansemmelink/goweb1/app"
+)
+
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{
+	"lint":     {usage: "[-funcs <file>] [-warn] <app files...>", run: lint},
+	"graph":    {usage: "[-funcs <file>] [-warn] [-format dot|mermaid] <app files...>", run: graph},
+	"describe": {usage: "[-funcs <file>] [-warn] <item id> <app files...>", run: describe},
+	"schema":   {usage: "[-o <file>]", run: schema},
+}
+
+func main() {
+	//the app package logs debug while loading, only show the warnings and errors
+	logger.SetGlobalWriter(logWriter{})
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command \"%s\"\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", os.Args[1], errorText(err))
+		os.Exit(1)
+	}
+}
+
+// errorText joins the messages of the error and its parents
+// (err.Error() of go-msvc/errors repeats parents when nested more than twice)
+func errorText(err error) string {
+	messages := []string{}
+	for err != nil {
+		e, ok := err.(errors.IError)
+		if !ok {
+			messages = append(messages, err.Error())
+			break
+		}
+		messages = append(messages, e.Message())
+		err = e.Parent()
+	}
+	return strings.Join(messages, " because ")
+}
+
+func usage() {
+	names := []string{}
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(os.Stderr, "Usage:\n")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %s %s %s\n", os.Args[0], name, commands[name].usage)
+	}
+}
+
+// appFlags are the flags used by all commands to load the app
+type appFlags struct {
+	*flag.FlagSet
+	funcsFile *string
+	warn      *bool
+}
+
+func newAppFlags(name string) appFlags {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	return appFlags{
+		FlagSet:   flags,
+		funcsFile: flags.String("funcs", "", "file with signatures of funcs registered by the app"),
+		warn:      flags.Bool("warn", false, "warn about unknown attributes and items instead of failing"),
+	}
+}
+
+// load creates the app with the declared funcs and loads the app files
+func (flags appFlags) load(appFiles []string) (app.App, error) {
+	if len(appFiles) == 0 {
+		return nil, errors.Errorf("missing app files")
+	}
+	unknown, dangling := app.UnknownAttributesFail, app.DanglingRefsFail
+	if *flags.warn {
+		unknown, dangling = app.UnknownAttributesWarn, app.DanglingRefsWarn
+	}
+	a := app.New(app.WithUnknownAttributes(unknown), app.WithDanglingRefs(dangling))
+	if *flags.funcsFile != "" {
+		if err := registerFuncs(a, *flags.funcsFile); err != nil {
+			return nil, errors.Wrapf(err, "failed to register funcs from %s", *flags.funcsFile)
+		}
+	}
+	if err := a.Load(appFiles...); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func lint(args []string) error {
+	flags := newAppFlags("lint")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	a, err := flags.load(flags.Args())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("ok: %d items\n", len(a.ItemIds()))
+	return nil
+}
+
+// logWriter prints warnings and errors logged by the app packages
+type logWriter struct{}
+
+func (logWriter) Write(r logger.Record) {
+	if r.Level <= logger.LevelError {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", r.Message)
+	}
+}
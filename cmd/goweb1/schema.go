@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+
+	"github.com/go-msvc/errors"
+	"github.com/jansemmelink/goweb1/app"
+)
+
+// schema writes the JSON Schema of app files, e.g. for editors to validate app.json
+func schema(args []string) error {
+	flags := flag.NewFlagSet("schema", flag.ContinueOnError)
+	outFile := flags.String("o", "", "output file (default stdout)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	buffer := bytes.NewBuffer(nil)
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(app.Schema()); err != nil {
+		return errors.Wrapf(err, "failed to encode schema")
+	}
+	if *outFile == "" {
+		_, err := os.Stdout.Write(buffer.Bytes())
+		return err
+	}
+	if err := os.WriteFile(*outFile, buffer.Bytes(), 0644); err != nil {
+		return errors.Wrapf(err, "failed to write %s", *outFile)
+	}
+	return nil
+}
@@ -3,18 +3,14 @@ module github.com/jansemmelink/goweb1
 go 1.20
 
 require (
+	github.com/go-msvc/data v1.0.2
 	github.com/go-msvc/errors v1.2.0
+	github.com/go-msvc/expression v1.2.0
 	github.com/go-msvc/logger v1.0.0
+	github.com/go-redis/redis v6.15.5+incompatible
 	github.com/google/uuid v1.3.0
 	github.com/gorilla/securecookie v1.1.1
 	github.com/gorilla/sessions v1.2.1
-	github.com/michaeljs1990/sqlitestore v0.0.0-20210507162135-8585425bc864
-)
-
-require (
-	github.com/go-msvc/data v1.0.2 // indirect
-	github.com/go-msvc/expression v1.2.0 // indirect
-	github.com/go-redis/redis v6.15.5+incompatible // indirect
-	github.com/mattn/go-sqlite3 v1.14.17 // indirect
-	github.com/rbcervilla/redisstore v1.1.0 // indirect
+	github.com/mattn/go-sqlite3 v1.14.17
+	golang.org/x/crypto v0.14.0
 )
@@ -0,0 +1,244 @@
+// Package metrics collects counters and histograms in memory
+// and writes them in the Prometheus text exposition format,
+// so they can be scraped from /metrics without running any other service
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DurationBuckets are upper bounds in seconds for latencies
+var DurationBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// SizeBuckets are upper bounds in bytes for sizes of e.g. session data
+var SizeBuckets = []float64{256, 1024, 4096, 16384, 65536, 262144, 1048576}
+
+// metric is a counter or histogram family with all its label values
+type metric interface {
+	name() string
+	write(w io.Writer)
+}
+
+var (
+	registryMutex sync.Mutex
+	registry      = map[string]metric{}
+)
+
+func register(m metric) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	if _, ok := registry[m.name()]; ok {
+		panic(fmt.Sprintf("metric %s already registered", m.name()))
+	}
+	registry[m.name()] = m
+}
+
+// WriteText writes all metrics in the text exposition format
+func WriteText(w io.Writer) {
+	registryMutex.Lock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	registryMutex.Unlock()
+	sort.Strings(names)
+	for _, name := range names {
+		registryMutex.Lock()
+		m := registry[name]
+		registryMutex.Unlock()
+		m.write(w)
+	}
+}
+
+// Handler serves all metrics, e.g. on /metrics
+func Handler() http.Handler {
+	return http.HandlerFunc(func(httpRes http.ResponseWriter, httpReq *http.Request) {
+		httpRes.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WriteText(httpRes)
+	})
+}
+
+// family has the values of a metric by label values
+type family struct {
+	metricName string
+	help       string
+	labelNames []string
+	mutex      sync.Mutex
+	values     map[string]interface{} //key is the joined label values, value depends on the type
+	labels     map[string][]string    //label values by key
+}
+
+func newFamily(name, help string, labelNames []string) family {
+	return family{
+		metricName: name,
+		help:       help,
+		labelNames: labelNames,
+		values:     map[string]interface{}{},
+		labels:     map[string][]string{},
+	}
+}
+
+func (f *family) name() string {
+	return f.metricName
+}
+
+// value returns the value for the label values, created with newValue() if not yet defined
+// it must be called with f.mutex locked
+func (f *family) value(labelValues []string, newValue func() interface{}) interface{} {
+	if len(labelValues) != len(f.labelNames) {
+		panic(fmt.Sprintf("metric %s expects labels %v but got %v", f.metricName, f.labelNames, labelValues))
+	}
+	key := strings.Join(labelValues, "\xff")
+	v, ok := f.values[key]
+	if !ok {
+		v = newValue()
+		f.values[key] = v
+		f.labels[key] = append([]string{}, labelValues...)
+	}
+	return v
+}
+
+// sortedKeys returns the keys of values in order of label values
+// (not of the keys, in which "\xff" sorts after longer values with the same prefix)
+func (f *family) sortedKeys() []string {
+	keys := make([]string, 0, len(f.values))
+	for key := range f.values {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := f.labels[keys[i]], f.labels[keys[j]]
+		for index := range a {
+			if a[index] != b[index] {
+				return a[index] < b[index]
+			}
+		}
+		return false
+	})
+	return keys
+}
+
+func (f *family) writeHeader(w io.Writer, metricType string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", f.metricName, escapeHelp(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.metricName, metricType)
+}
+
+// labelText formats label pairs, e.g. `{method="GET",status="200"}`
+// extra is appended as is, e.g. `le="0.5"`
+func (f *family) labelText(labelValues []string, extra string) string {
+	pairs := []string{}
+	for index, name := range f.labelNames {
+		pairs = append(pairs, name+"=\""+escapeLabel(labelValues[index])+"\"")
+	}
+	if extra != "" {
+		pairs = append(pairs, extra)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// Counter counts events by label values
+type Counter struct {
+	family
+}
+
+// NewCounter registers a counter, e.g. NewCounter("http_requests_total", "...", "method", "status")
+func NewCounter(name, help string, labelNames ...string) *Counter {
+	c := &Counter{family: newFamily(name, help, labelNames)}
+	register(c)
+	return c
+}
+
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *Counter) Add(delta float64, labelValues ...string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	v := c.value(labelValues, func() interface{} { return new(float64) }).(*float64)
+	*v += delta
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.writeHeader(w, "counter")
+	for _, key := range c.sortedKeys() {
+		fmt.Fprintf(w, "%s%s %s\n", c.metricName, c.labelText(c.labels[key], ""), formatValue(*c.values[key].(*float64)))
+	}
+}
+
+// Histogram counts observed values, e.g. latencies, in buckets by label values
+type Histogram struct {
+	family
+	buckets []float64
+}
+
+type histogramValue struct {
+	counts []uint64 //per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram registers a histogram with the bucket upper bounds in ascending order
+func NewHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	h := &Histogram{family: newFamily(name, help, labelNames), buckets: buckets}
+	register(h)
+	return h
+}
+
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	v := h.value(labelValues, func() interface{} { return &histogramValue{counts: make([]uint64, len(h.buckets))} }).(*histogramValue)
+	for index, bound := range h.buckets {
+		if value <= bound {
+			v.counts[index]++
+			break
+		}
+	}
+	v.count++
+	v.sum += value
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.writeHeader(w, "histogram")
+	for _, key := range h.sortedKeys() {
+		labelValues := h.labels[key]
+		v := h.values[key].(*histogramValue)
+		cumulative := uint64(0)
+		for index, bound := range h.buckets {
+			cumulative += v.counts[index]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, h.labelText(labelValues, "le=\""+formatValue(bound)+"\""), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, h.labelText(labelValues, "le=\"+Inf\""), v.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.metricName, h.labelText(labelValues, ""), formatValue(v.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.metricName, h.labelText(labelValues, ""), v.count)
+	}
+}
+
+func formatValue(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "\n", "\\n").Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\"", "\\\"").Replace(s)
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// unregister removes metrics registered by a test
+func unregister(t *testing.T, names ...string) {
+	t.Cleanup(func() {
+		registryMutex.Lock()
+		defer registryMutex.Unlock()
+		for _, name := range names {
+			delete(registry, name)
+		}
+	})
+}
+
+func TestWriteText(t *testing.T) {
+	testRequests := NewCounter("test_requests_total", "Requests by \"path\"\nand status \\ code.", "path", "status")
+	testDuration := NewHistogram("test_duration_seconds", "Duration.", []float64{1, 2, 5}, "item")
+	testEvents := NewCounter("test_events_total", "Events without labels.")
+	unregister(t, "test_requests_total", "test_duration_seconds", "test_events_total")
+	testRequests.Inc("/b", "200")
+	testRequests.Inc("/a\"quoted\"\\path\n", "500")
+	testRequests.Add(2, "/a", "200")
+	testEvents.Inc()
+	for _, value := range []float64{0.5, 1, 3, 10} {
+		testDuration.Observe(value, "home")
+	}
+	testDuration.Observe(1.5, "about")
+
+	buffer := bytes.NewBuffer(nil)
+	WriteText(buffer)
+	expected := strings.Join([]string{
+		`# HELP test_duration_seconds Duration.`,
+		`# TYPE test_duration_seconds histogram`,
+		`test_duration_seconds_bucket{item="about",le="1"} 0`,
+		`test_duration_seconds_bucket{item="about",le="2"} 1`,
+		`test_duration_seconds_bucket{item="about",le="5"} 1`,
+		`test_duration_seconds_bucket{item="about",le="+Inf"} 1`,
+		`test_duration_seconds_sum{item="about"} 1.5`,
+		`test_duration_seconds_count{item="about"} 1`,
+		`test_duration_seconds_bucket{item="home",le="1"} 2`,
+		`test_duration_seconds_bucket{item="home",le="2"} 2`,
+		`test_duration_seconds_bucket{item="home",le="5"} 3`,
+		`test_duration_seconds_bucket{item="home",le="+Inf"} 4`,
+		`test_duration_seconds_sum{item="home"} 14.5`,
+		`test_duration_seconds_count{item="home"} 4`,
+		`# HELP test_events_total Events without labels.`,
+		`# TYPE test_events_total counter`,
+		`test_events_total 1`,
+		`# HELP test_requests_total Requests by "path"\nand status \\ code.`,
+		`# TYPE test_requests_total counter`,
+		`test_requests_total{path="/a",status="200"} 2`,
+		`test_requests_total{path="/a\"quoted\"\\path\n",status="500"} 1`,
+		`test_requests_total{path="/b",status="200"} 1`,
+	}, "\n") + "\n"
+	if buffer.String() != expected {
+		t.Fatalf("wrote:\n%s\ninstead of:\n%s", buffer.String(), expected)
+	}
+}
+
+func TestLabelCount(t *testing.T) {
+	testCounter := NewCounter("test_counter_total", "Counter.", "path", "status")
+	testHistogram := NewHistogram("test_histogram_seconds", "Histogram.", DurationBuckets, "item")
+	unregister(t, "test_counter_total", "test_histogram_seconds")
+	tests := []struct {
+		name string
+		fn   func()
+	}{
+		{"counter without labels", func() { testCounter.Inc() }},
+		{"counter with too many labels", func() { testCounter.Inc("/", "200", "extra") }},
+		{"histogram without labels", func() { testHistogram.Observe(1) }},
+		{"duplicate name", func() { NewCounter("test_counter_total", "Again.") }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("did not panic")
+				}
+			}()
+			test.fn()
+		})
+	}
+}
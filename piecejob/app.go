@@ -5,14 +5,14 @@ import (
 	"regexp"
 
 	"github.com/go-msvc/errors"
-	"github.com/go-msvc/logger"
 	"github.com/jansemmelink/goweb1/app"
 )
 
-var log = logger.New().WithLevel(logger.LevelDebug)
-
-func App() (app.App, error) {
-	piecejobApp := app.New()
+// App creates the piecejob app and loads its items from the JSON files,
+// directories or glob patterns, by default "../app.json"
+func App(appFiles ...string) (app.App, error) {
+	//some menu items refer to items that are not yet implemented, e.g. "add-skill"
+	piecejobApp := app.New(app.WithDanglingRefs(app.DanglingRefsWarn))
 
 	//todo: install modules
 	piecejobApp.RegisterFunc("getProfile", getProfile)
@@ -26,9 +26,11 @@ func App() (app.App, error) {
 	//piecejobApp.Register("some-id", myFunc)
 	//piecejobApp.Register("other-id", myType{})
 
-	//todo: load app from file
-	if err := piecejobApp.Load("../app.json"); err != nil {
-		return nil, errors.Wrapf(err, "failed to load app.json")
+	if len(appFiles) == 0 {
+		appFiles = []string{"../app.json"}
+	}
+	if err := piecejobApp.Load(appFiles...); err != nil {
+		return nil, errors.Wrapf(err, "failed to load app from %v", appFiles)
 	}
 	return piecejobApp, nil
 }
@@ -39,14 +41,14 @@ type GetMySkillsReq struct {
 }
 
 func getMySkills(ctx context.Context, req GetMySkillsReq) ([]string, error) {
-	log.Debugf("req: (%T)%+v", req, req)
+	app.LoggerFrom(ctx).Debugf("req: (%T)%+v", req, req)
 	mySkillsList := []string{"Cleaner", "Painter"}
 	return mySkillsList, nil
 }
 
 // list returning simple list of strings
 func listOfSkills(ctx context.Context, req GetMySkillsReq) (app.ColumnList, error) {
-	log.Debugf("req: (%T)%+v", req, req)
+	app.LoggerFrom(ctx).Debugf("req: (%T)%+v", req, req)
 	skills := []app.ColumnItem{
 		{"Skill": "Cleaner"},
 		{"Skill": "Painter"},
@@ -61,7 +63,7 @@ type job struct {
 	Type string
 }
 
-// profile keyed on national id
+// profile keyed on user id
 var profiles = map[string]Profile{}
 
 type Profile struct {
@@ -73,7 +75,7 @@ type Profile struct {
 
 func (p Profile) Validate() error {
 	if !natIdRegex.MatchString(p.NatId) {
-		return errors.Errorf("invalid natId(%s)", p.NatId)
+		return app.NewError(app.KindValidation, "invalid national id \"%s\" (expect 13 digits)", p.NatId)
 	}
 	return nil
 } //Profile.Validate()
@@ -82,27 +84,24 @@ const natIdPattern = `[0-9]{13}`
 
 var natIdRegex = regexp.MustCompile("^" + natIdPattern + "$")
 
-func getProfile(ctx context.Context, natId string) (Profile, error) {
-	if !natIdRegex.MatchString(natId) {
-		return Profile{}, errors.Errorf("invalid natId=\"%s\"", natId)
-	}
-	p, ok := profiles[natId]
+// getProfile returns the profile of the logged in user, new users start with an empty profile
+func getProfile(ctx context.Context, user *app.User) (Profile, error) {
+	p, ok := profiles[user.ID]
 	if !ok {
 		return Profile{
-			NatId: natId,
+			Name: user.Name,
 		}, nil
 	}
-	log.Debugf("Retrieved profile(%s):%+v", natId, p)
+	app.LoggerFrom(ctx).Debugf("Retrieved profile(%s)", user.ID) //not the values, which include national ids
 	return p, nil
 }
 
-func updProfile(ctx context.Context, p Profile) error {
+func updProfile(ctx context.Context, user *app.User, p Profile) error {
 	if err := p.Validate(); err != nil {
 		return errors.Wrapf(err, "invalid profile")
 	}
-	//todo: need to get id in req
-	log.Debugf("Saving profile:%+v", p)
-	profiles[p.NatId] = p
+	app.LoggerFrom(ctx).Debugf("Saving profile(%s)", user.ID) //not the values, which include national ids
+	profiles[user.ID] = p
 	return nil
 }
 
@@ -137,7 +136,7 @@ func updJob(ctx context.Context, j Job) error {
 	// 	return errors.Wrapf(err, "invalid profile")
 	// }
 	//todo: need to get id in req
-	log.Debugf("Saving job:%+v", j)
+	app.LoggerFrom(ctx).Debugf("Saving job:%+v", j)
 	jobs[j.Id] = j
 	return nil
 }
@@ -0,0 +1,54 @@
+package piecejob
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/go-msvc/errors"
+	"github.com/jansemmelink/goweb1/app"
+)
+
+// data is kept in memory while running
+// and saved to a JSON file on shutdown, so that it survives a restart
+type data struct {
+	Profiles map[string]Profile `json:"profiles"`
+	Jobs     map[string]Job     `json:"jobs"`
+}
+
+// LoadData reads profiles and jobs from the file if it exists
+func LoadData(ctx context.Context, filename string) error {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			app.LoggerFrom(ctx).Infof("No data in %s yet", filename)
+			return nil
+		}
+		return errors.Wrapf(err, "failed to read data file")
+	}
+	var d data
+	if err := json.Unmarshal(content, &d); err != nil {
+		return errors.Wrapf(err, "invalid data file %s", filename)
+	}
+	if d.Profiles != nil {
+		profiles = d.Profiles
+	}
+	if d.Jobs != nil {
+		jobs = d.Jobs
+	}
+	app.LoggerFrom(ctx).Infof("Loaded %d profiles and %d jobs from %s", len(profiles), len(jobs), filename)
+	return nil
+} //LoadData()
+
+// SaveData writes profiles and jobs to the file
+func SaveData(ctx context.Context, filename string) error {
+	content, err := json.MarshalIndent(data{Profiles: profiles, Jobs: jobs}, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "failed to encode data")
+	}
+	if err := os.WriteFile(filename, content, 0600); err != nil {
+		return errors.Wrapf(err, "failed to write data file")
+	}
+	app.LoggerFrom(ctx).Infof("Saved %d profiles and %d jobs to %s", len(profiles), len(jobs), filename)
+	return nil
+} //SaveData()
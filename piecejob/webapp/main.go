@@ -1,16 +1,54 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/jansemmelink/goweb1/piecejob"
 	"github.com/jansemmelink/goweb1/web"
 )
 
 func main() {
-	app, err := piecejob.App()
+	//optional args are app files/dirs to load
+	app, err := piecejob.App(os.Args[1:]...)
 	if err != nil {
 		panic(fmt.Sprintf("%+v", err))
 	}
-	web.New(app).Run()
+
+	//web server config from environment, e.g. PORT=8080 SESSION_STORE=redis
+	config, err := web.ConfigFromEnv()
+	if err != nil {
+		panic(fmt.Sprintf("%+v", err))
+	}
+	//do not log national ids unless LOG_REDACT says otherwise
+	if len(config.LogRedact) == 0 {
+		config.LogRedact = []string{"NationalId"}
+	}
+	server, err := web.New(app, config)
+	if err != nil {
+		panic(fmt.Sprintf("%+v", err))
+	}
+
+	//optional hot reload when app files change, e.g. APP_WATCH=2s
+	//started after web.New() which sets the log level of the app
+	if watch := os.Getenv("APP_WATCH"); watch != "" {
+		interval, err := time.ParseDuration(watch)
+		if err != nil || interval <= 0 {
+			panic(fmt.Sprintf("invalid APP_WATCH=\"%s\", expecting a positive duration, e.g. 2s", watch))
+		}
+		if err := app.Watch(context.Background(), interval); err != nil {
+			panic(fmt.Sprintf("%+v", err))
+		}
+	}
+
+	//optional file to keep piecejob data between restarts, e.g. PIECEJOB_DATA=./data.json
+	if dataFile := os.Getenv("PIECEJOB_DATA"); dataFile != "" {
+		server.OnStart(func(ctx context.Context) error { return piecejob.LoadData(ctx, dataFile) })
+		server.OnStop(func(ctx context.Context) error { return piecejob.SaveData(ctx, dataFile) })
+	}
+	if err := server.Run(); err != nil {
+		panic(fmt.Sprintf("%+v", err))
+	}
 }
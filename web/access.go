@@ -0,0 +1,32 @@
+package web
+
+import (
+	"context"
+
+	"github.com/gorilla/sessions"
+	"github.com/jansemmelink/goweb1/app"
+)
+
+// checkAccess checks that the user of the session may enter the item (see app.ItemAccess)
+// when a login is required, the item is kept in the session as "return_item",
+// so that the user continues on it after login (see signIn())
+func (w *webApp) checkAccess(ctx context.Context, itemId string, item app.AppItem) error {
+	err := item.Access().Check(app.UserFrom(ctx))
+	if err == nil {
+		return nil
+	}
+	if app.KindOf(err) == app.KindAuthRequired {
+		session := ctx.Value(app.CtxSession{}).(*sessions.Session)
+		session.Values["return_item"] = itemId
+	}
+	return err
+} //webApp.checkAccess()
+
+// loginPath is the page where users log in, or "" when Config.Auth has none
+func (w *webApp) loginPath() string {
+	switch w.config.Auth {
+	case "password", "otp":
+		return "/login"
+	}
+	return ""
+}
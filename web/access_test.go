@@ -0,0 +1,82 @@
+package web
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const testAccessApp = `{
+	"home":{
+		"menu":{
+			"title":{"":"Home"},
+			"items":[
+				{"caption":{"":"Secret"}, "next":[{"item":"secret"}]},
+				{"caption":{"":"Admin"}, "next":[{"item":"admin"}]}
+			]
+		}
+	},
+	"secret":{
+		"auth":"required",
+		"menu":{
+			"title":{"":"Secret Page"},
+			"items":[{"caption":{"":"Home"}, "next":[{"item":"home"}]}]
+		}
+	},
+	"admin":{
+		"roles":["admin"],
+		"menu":{
+			"title":{"":"Admin Page"},
+			"items":[{"caption":{"":"Home"}, "next":[{"item":"home"}]}]
+		}
+	}
+}`
+
+// testLink returns the URL of the menu link with the caption
+func testLink(t *testing.T, body string, caption string) string {
+	match := regexp.MustCompile(`href="(/\?next=[^"]+)">` + regexp.QuoteMeta(caption) + `<`).FindStringSubmatch(body)
+	if match == nil {
+		t.Fatalf("no link to %s in:\n%s", caption, body)
+	}
+	return match[1]
+}
+
+func TestAccess(t *testing.T) {
+	httpServer, client := testServer(t, testAccessApp, DefaultConfig(), nil)
+	_, body := testGet(t, client, httpServer.URL+"/")
+
+	//without a user, the item goes to login
+	res, body := testGet(t, client, httpServer.URL+testLink(t, body, "Secret"))
+	if res.StatusCode != http.StatusSeeOther || res.Header.Get("Location") != "/login" {
+		t.Fatalf("GET secret -> %d to \"%s\" instead of login:\n%s", res.StatusCode, res.Header.Get("Location"), body)
+	}
+	res, body = testGet(t, client, httpServer.URL+"/login")
+	if res.StatusCode != http.StatusOK || !strings.Contains(body, "Please log in to continue.") {
+		t.Fatalf("GET login -> %d without notice:\n%s", res.StatusCode, body)
+	}
+
+	//after login it continues on the item
+	form := testFormTokens(body, url.Values{
+		"Name":     {"Test"},
+		"Email":    {"test@example.com"},
+		"Password": {"password1"},
+		"Confirm":  {"password1"},
+	})
+	res, body = testPost(t, client, httpServer.URL+"/register", form)
+	if res.StatusCode != http.StatusSeeOther || res.Header.Get("Location") != "/?next=return" {
+		t.Fatalf("POST register -> %d to \"%s\" instead of return:\n%s", res.StatusCode, res.Header.Get("Location"), body)
+	}
+	res, body = testGet(t, client, httpServer.URL+"/?next=return")
+	if res.StatusCode != http.StatusOK || !strings.Contains(body, "Secret Page") {
+		t.Fatalf("GET return -> %d without the secret page:\n%s", res.StatusCode, body)
+	}
+
+	//a user without the role may not enter
+	_, body = testGet(t, client, httpServer.URL+"/?next=home")
+	res, body = testGet(t, client, httpServer.URL+testLink(t, body, "Admin"))
+	if res.StatusCode != http.StatusForbidden || strings.Contains(body, "Admin Page") {
+		t.Fatalf("GET admin -> %d instead of %d:\n%s", res.StatusCode, http.StatusForbidden, body)
+	}
+}
@@ -4,192 +4,397 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"net"
 	"net/http"
-	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/go-msvc/errors"
-	"github.com/go-msvc/logger"
-	"github.com/go-redis/redis"
 	"github.com/google/uuid"
 	"github.com/gorilla/securecookie"
 	"github.com/gorilla/sessions"
 	"github.com/jansemmelink/goweb1/app"
-	"github.com/michaeljs1990/sqlitestore"
-	"github.com/rbcervilla/redisstore"
 )
 
-var log = logger.New().WithLevel(logger.LevelDebug)
-
 type App interface {
+	//Run starts the server and waits for SIGINT or SIGTERM to shut it down gracefully
 	Run() error
+
+	//Start serving in the background, returns once listening
+	//Shutdown stops accepting requests and waits for in-flight requests
+	//to complete until ctx is done, then stops the hooks and closes the session store
+	Start(ctx context.Context) error
+	Shutdown(ctx context.Context) error
+
+	//OnStart hooks are called in Start() before serving, e.g. to open data stores
+	//OnStop hooks are called in reverse order in Shutdown() after the last request
+	OnStart(hook Hook)
+	OnStop(hook Hook)
 }
 
-func New(app app.App) App {
+type Hook func(ctx context.Context) error
+
+// New creates the web server for the app
+// it fails on invalid config before anything is served
+func New(app app.App, config Config) (App, error) {
+	if err := config.Validate(); err != nil {
+		return nil, errors.Wrapf(err, "invalid config")
+	}
+	setLogLevel(logLevels[config.LogLevel])
+	if config.SessionExpiredItem != "" {
+		if _, ok := app.GetItem("", config.SessionExpiredItem); !ok {
+			return nil, errors.Errorf("unknown SessionExpiredItem \"%s\"", config.SessionExpiredItem)
+		}
+	}
+
 	// Hash keys should be at least 32 bytes long
-	hashKey := []byte(os.Getenv("HASH_KEY"))
-	if len(hashKey) == 0 {
-		hashKey = securecookie.GenerateRandomKey(32)
+	if len(config.HashKey) == 0 {
+		config.HashKey = securecookie.GenerateRandomKey(32)
 		log.Errorf("Using random HASH_KEY")
 	}
 
 	// Block keys should be 16 bytes (AES-128) or 32 bytes (AES-256) long.
 	// Shorter keys may weaken the encryption used.
-	var blockKey = []byte(os.Getenv("BLOCK_KEY"))
-	if len(blockKey) == 0 {
-		blockKey = securecookie.GenerateRandomKey(32)
+	if len(config.BlockKey) == 0 {
+		config.BlockKey = securecookie.GenerateRandomKey(32)
 		log.Errorf("Using random BLOCK_KEY")
 	}
 
-	return webApp{
+	resources, err := newResources(config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid resources")
+	}
+
+	var users UserStore
+	if config.Auth == "password" {
+		userFactory, _ := userStoreFactory(config.UserStore)
+		if users, err = userFactory(config); err != nil {
+			return nil, errors.Wrapf(err, "failed to create %s user store", config.UserStore)
+		}
+	}
+
+	var otpSender OTPSender
+	if config.Auth == "otp" {
+		senderFactory, _ := otpSenderFactory(config.OTPSender)
+		if otpSender, err = senderFactory(config); err != nil {
+			return nil, errors.Wrapf(err, "failed to create %s OTP sender", config.OTPSender)
+		}
+	}
+
+	factory, _ := sessionStoreFactory(config.SessionStore)
+	store, err := factory(config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create %s session store", config.SessionStore)
+	}
+	w := &webApp{
 		app:          app,
-		cookieName:   "MyCookieName",
-		hashKey:      hashKey,
-		blockKey:     blockKey,
-		cookieCutter: securecookie.New(hashKey, blockKey),
-		sessionStore: nil,
+		config:       config,
+		cookieCutter: securecookie.New(config.HashKey, config.BlockKey),
+		store:        store,
+		sessionStore: &serverSideStore{
+			store: store,
+			options: sessions.Options{
+				Path:     "/",
+				Domain:   config.CookieDomain,
+				MaxAge:   int(config.SessionMaxAge / time.Second),
+				Secure:   config.CookieSecure,
+				HttpOnly: true,
+			},
+		},
+		users:     users,
+		otpSender: otpSender,
+		otpLimits: newOTPLimiter(config),
+		resources: resources,
+		mux:       http.NewServeMux(),
 	}
+	w.mux.HandleFunc("/", w.hdlr())
+	w.mux.Handle(resourcesPath, resources.hdlr())
+	w.handleHealth()
+	w.handleMetrics()
+	w.handleAuth()
+	return w, nil
 } //New()
 
 type webApp struct {
 	app          app.App
-	cookieName   string
-	hashKey      []byte
-	blockKey     []byte
+	config       Config
 	cookieCutter securecookie.Codec
+	store        SessionStore
 	sessionStore sessions.Store
+	users        UserStore //nil without password auth
+	otpSender    OTPSender //nil without otp auth
+	otpLimits    *otpLimiter
+	resources    *resources
+
+	mux         *http.ServeMux
+	server      *http.Server
+	serveErr    chan error //http server result when stopped
+	stopCleanup chan struct{}
+	onStart     []Hook
+	onStop      []Hook
+}
+
+func (w *webApp) OnStart(hook Hook) {
+	w.onStart = append(w.onStart, hook)
+}
+
+func (w *webApp) OnStop(hook Hook) {
+	w.onStop = append(w.onStop, hook)
 }
 
-func (w webApp) Run() error {
-	//validation
-	if len(w.hashKey) != 32 {
-		return errors.Errorf("HASH_KEY not 32 long")
+func (w *webApp) Run() error {
+	if err := w.Start(context.Background()); err != nil {
+		return err
 	}
-	if len(w.blockKey) != 32 {
-		return errors.Errorf("BLOCK_KEY not 32 bytes long")
+
+	//App Runner, docker, k8s etc send SIGTERM before stopping the instance
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	var serveErr error
+	select {
+	case <-ctx.Done():
+		log.Infof("Shutting down...")
+	case serveErr = <-w.serveErr:
+		log.Errorf("http server stopped: %+v", serveErr)
 	}
 
-	//todo: option to replace default local session store in prod
-	//for prod e.g., replace this e.g. with dynamo db like
-	//see https://github.com/gorilla/sessions for list of options
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), w.config.ShutdownTimeout)
+	defer cancel()
+	if err := w.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if serveErr != nil {
+		return errors.Wrapf(serveErr, "http server failed")
+	}
+	return nil
+} //webApp.Run()
 
-	//-======  sqlite  =======-
-	if true {
+func (w *webApp) Start(ctx context.Context) error {
+	if w.server != nil {
+		return errors.Errorf("already started")
+	}
+	for _, hook := range w.onStart {
+		if err := hook(ctx); err != nil {
+			return errors.Wrapf(err, "start hook failed")
+		}
+	}
+
+	//listen before returning, so that the address in use is reported
+	listener, err := net.Listen("tcp", w.config.ListenAddr)
+	if err != nil {
+		return errors.Wrapf(err, "failed to listen on %s", w.config.ListenAddr)
+	}
+	w.server = &http.Server{
+		Addr:    w.config.ListenAddr,
+		Handler: instrument(w.mux),
+	}
+	w.serveErr = make(chan error, 1)
+	go func() {
 		var err error
-		w.sessionStore, err = sqlitestore.NewSqliteStore(
-			"./database",
-			"sessions",
-			"/",
-			3600,
-			[]byte("<SecretKey>"))
-		if err != nil {
-			return errors.Wrapf(err, "failed to create session store")
+		if w.config.TLSCertFile != "" {
+			err = w.server.ServeTLS(listener, w.config.TLSCertFile, w.config.TLSKeyFile)
+		} else {
+			err = w.server.Serve(listener)
 		}
-	} else {
-		client := redis.NewClient(&redis.Options{
-			Addr: "localhost:6379",
-		})
-		store, err := redisstore.NewRedisStore(client)
-		if err != nil {
-			panic(fmt.Sprintf("failed to create redis store: %+v", err))
+		if err != http.ErrServerClosed {
+			w.serveErr <- err
 		}
+		close(w.serveErr)
+	}()
+
+	w.stopCleanup = make(chan struct{})
+	go cleanupSessions(w.store, w.config.SessionCleanupInterval, w.stopCleanup)
+	log.Infof("Started the server: %s", w.config)
+	return nil
+} //webApp.Start()
+
+func (w *webApp) Shutdown(ctx context.Context) error {
+	if w.server == nil {
+		return errors.Errorf("not started")
+	}
+	errs := []string{}
+	if err := w.server.Shutdown(ctx); err != nil {
+		//ctx ended before all requests completed
+		errs = append(errs, fmt.Sprintf("http server shutdown: %v", err))
+	}
+	close(w.stopCleanup)
+
+	for index := len(w.onStop) - 1; index >= 0; index-- {
+		if err := w.onStop[index](ctx); err != nil {
+			errs = append(errs, fmt.Sprintf("stop hook: %v", err))
+		}
+	}
 
-		// Example changing configuration for sessions
-		store.KeyPrefix("session_")
-		store.Options(sessions.Options{
-			Path:   "/",
-			Domain: "example.com",
-			MaxAge: 86400 * 60,
-		})
-		w.sessionStore = store
-	} //scope
-
-	//setup and start HTTP server
-	http.HandleFunc("/", w.hdlr())
-	fmt.Println("Starting the server on :3000...")
-	if err := http.ListenAndServe(":3000", nil); err != nil {
-		return errors.Wrapf(err, "http server failed")
+	//sessions are saved at the end of each request, so after the last
+	//request completed, the store can be closed
+	if err := w.store.Close(); err != nil {
+		errs = append(errs, fmt.Sprintf("session store close: %v", err))
 	}
+	if w.users != nil {
+		if err := w.users.Close(); err != nil {
+			errs = append(errs, fmt.Sprintf("user store close: %v", err))
+		}
+	}
+	if w.otpSender != nil {
+		if err := w.otpSender.Close(); err != nil {
+			errs = append(errs, fmt.Sprintf("OTP sender close: %v", err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Errorf("shutdown failed: %s", strings.Join(errs, ", "))
+	}
+	log.Infof("Server stopped")
 	return nil
-} //webapp.Run()
+} //webApp.Shutdown()
 
+// ClientData is kept in the cookie, so it outlives the session on the server
 type ClientData struct {
 	DeviceID string
+	LastItem string //item last rendered, to resume after the session expired
 }
 
 type CtxClientData struct{}
 
-func (w webApp) hdlr() func(httpRes http.ResponseWriter, httpReq *http.Request) {
+func (w *webApp) hdlr() func(httpRes http.ResponseWriter, httpReq *http.Request) {
 	return func(httpRes http.ResponseWriter, httpReq *http.Request) {
-		log.Debugf("HTTP %s %s", httpReq.Method, httpReq.URL.Path)
-
 		if httpReq.URL.Path != "/" {
 			http.Error(httpRes, fmt.Sprintf("path \"%s\" not found", httpReq.URL.Path), http.StatusNotFound)
 			return
 		}
 
-		//todo: context logger
-		//todo: log current item id in each line of the logger
-
+		//the request logger stamps each line with request id, device, version and item
 		ctx := w.userContext(httpReq)
+		log := app.LoggerFrom(ctx)
+		log.Debugf("HTTP %s %s", httpReq.Method, httpReq.URL.Path)
 		session := ctx.Value(app.CtxSession{}).(*sessions.Session)
 
+		switch sessionState := ctx.Value(CtxSessionState{}).(SessionState); sessionState {
+		case SessionUnavailable:
+			w.fail(ctx, httpReq, httpRes, errors.Errorf("session store unavailable"))
+			return
+		case SessionExpired, SessionCorrupt:
+			w.sessionExpired(ctx, httpReq, httpRes, sessionState)
+			return
+		}
+
 		//load the currect app item to display/process
 		currentItemId, ok := session.Values["current_item"].(string)
 		if !ok || currentItemId == "" {
 			currentItemId = "home"
 		}
 
-		//todo: also check app version and redirect in load-balancer to correct app version
+		//sessions continue on the app version they started with, so that a reload
+		//does not break pages/links already rendered from the previous version
 		//todo: also check time when item was entered and discard if older than X
-		//to protect against users from long ago suddenly waking after that version
-		//of app was retired/changed
-		currentItem, ok := w.app.GetItem(currentItemId)
+		appVersion, ok := session.Values["app_version"].(string)
+		if !ok || appVersion == "" {
+			appVersion = w.app.Version()
+			session.Values["app_version"] = appVersion
+		}
+		logField(ctx, "version", appVersion)
+		logField(ctx, "item", currentItemId)
+		currentItem, ok := w.app.GetItem(appVersion, currentItemId)
 		if !ok {
-			//unknown item - likely an internal error or old app phased out
-			log.Errorf("unknown current_item:\"%s\"", currentItemId)
-
-			//do not just jump home - rather tell user and redirect home
+			//unknown item - likely an internal error or old app version retired
+			//do not just jump home - rather tell user and start a new session
 			//so it does not appear like continuity break if there was really a fault
-			redirect(httpRes, "Sorry - Session Terminated."+
-				"Click to start a new session",
-				"Start", "/")
+			w.fail(ctx, httpReq, httpRes, app.NewError(app.KindSessionExpired, "unknown current_item:\"%s\" in app version \"%s\"", currentItemId, appVersion))
+			return
+		}
+		if err := w.checkAccess(ctx, currentItemId, currentItem); err != nil {
+			//e.g. roles changed since the item was entered
+			w.fail(ctx, httpReq, httpRes, errors.Wrapf(err, "cannot stay on item(%s)", currentItemId))
 			return
 		}
 
 		switch httpReq.Method {
 		case http.MethodPost:
+			if err := w.checkCSRF(ctx, httpReq); err != nil {
+				w.fail(ctx, httpReq, httpRes, err)
+				return
+			}
+			pageSessionData, ok := w.postedPage(ctx, httpReq, currentItemId)
+			if !ok {
+				//form of another page, e.g. resubmitted or from an old tab:
+				//do not apply it to the current item, rather show the current page again
+				itemProcesses.Inc(currentItemId, "stale")
+				w.renderInPlace(ctx, httpReq, httpRes, http.StatusConflict, currentItemId, currentItem)
+				return
+			}
+			ctx = context.WithValue(ctx, app.CtxPageData{}, pageSessionData)
 			log.Debugf("processing...")
 			nextItemId, err := currentItem.Process(ctx, httpReq)
+			itemProcesses.Inc(currentItemId, result(err))
+			if err != nil && app.KindOf(err) == app.KindValidation {
+				//show the form again with what to correct
+				log.Errorf("invalid input on item(%s): %+v", currentItemId, err)
+				ctx = context.WithValue(ctx, app.CtxFormError{}, app.ErrorMessage(err))
+				ctx = context.WithValue(ctx, app.CtxFormValues{}, httpReq.PostForm)
+				w.renderInPlace(ctx, httpReq, httpRes, errorStatus(app.KindValidation), currentItemId, currentItem)
+				return
+			}
 			if err != nil {
-				log.Errorf("processing failed: %+v", err)
-				redirect(httpRes, "failed to process input", "home", "/") //todo: retries etc...
+				w.fail(ctx, httpReq, httpRes, errors.Wrapf(err, "processing failed"))
 				return
 			}
 			log.Debugf("processing done, next=\"%s\"", nextItemId)
 			if nextItemId == "" {
-				log.Errorf("processing succeeded but did not return nextItemId: %+v", err)
-				redirect(httpRes, "failed to process input", "home", "/") //todo: retries etc...
+				w.fail(ctx, httpReq, httpRes, errors.Errorf("processing succeeded but did not return nextItemId"))
 				return
 			}
-			if currentItemId, currentItem, err = w.navigateTo(ctx, nextItemId); err != nil {
-				log.Errorf("failed to nav to %s: %+v", nextItemId, err)
-				redirect(httpRes, "failed to navigate", "home", "/")
+			if currentItemId, _, err = w.navigateTo(ctx, nextItemId); err != nil {
+				w.fail(ctx, httpReq, httpRes, errors.Wrapf(err, "failed to nav to %s", nextItemId))
 				return
 			}
 
-			//navigate to next
-			log.Errorf("NOT YET NAV AFTER POST!!!")
+			//post/redirect/get: the next item is rendered on GET, so that reload
+			//and back in the browser do not post the form again
+			//the processed page is forgotten, so its token is not accepted again
+			//when the next item is the same item
+			session.Values["current_item"] = currentItemId
+			delete(session.Values, "page_data")
+			if err := session.Save(httpReq, httpRes); err != nil {
+				w.fail(ctx, httpReq, httpRes, errors.Wrapf(err, "failed to save session after processing"))
+				return
+			}
+			w.setCookie(ctx, httpRes, ctx.Value(CtxClientData{}).(ClientData))
+			http.Redirect(httpRes, httpReq, "/", http.StatusSeeOther)
+			return
 
 		case http.MethodGet:
 			//navigate from menu if GET with ?next=<next item uuid>
 			if nextItemUUID := httpReq.URL.Query().Get("next"); nextItemUUID != "" {
-				//special case:
-				if nextItemUUID == "home" {
+				//special cases:
+				if nextItemUUID == "resume" && w.config.SessionResume {
+					//continue on the item of the expired session
+					clientData := ctx.Value(CtxClientData{}).(ClientData)
+					if clientData.LastItem != "" {
+						var err error
+						if currentItemId, currentItem, err = w.navigateTo(ctx, clientData.LastItem); err != nil {
+							w.fail(ctx, httpReq, httpRes, errors.Wrapf(err, "failed to resume %s", clientData.LastItem))
+							return
+						}
+					}
+				} else if nextItemUUID == "return" {
+					//continue on the item that required login (see signIn())
+					if returnItemId, ok := session.Values["return_item"].(string); ok {
+						delete(session.Values, "return_item")
+						var err error
+						if currentItemId, currentItem, err = w.navigateTo(ctx, returnItemId); err != nil {
+							w.fail(ctx, httpReq, httpRes, errors.Wrapf(err, "failed to return to %s", returnItemId))
+							return
+						}
+					}
+				} else if nextItemUUID == "home" {
 					//reset and start over
 					var err error
 					currentItemId, currentItem, err = w.navigateTo(ctx, "home")
 					if err != nil {
-						panic(fmt.Sprintf("failed to nav home: %+v", err))
+						//e.g. app reloaded without a valid home
+						w.fail(ctx, httpReq, httpRes, errors.Wrapf(err, "failed to nav home"))
+						return
 					}
 				} else {
 					//can only apply if page stored any links
@@ -206,8 +411,7 @@ func (w webApp) hdlr() func(httpRes http.ResponseWriter, httpReq *http.Request)
 								log.Debugf("next:\"%s\"", nextItemId)
 								currentItemId, currentItem, err = w.navigateTo(ctx, nextItemId)
 								if err != nil {
-									log.Errorf("failed to nav to %s: %+v", nextItemId, err)
-									redirect(httpRes, "failed to process input", "home", "/") //todo: retries etc...
+									w.fail(ctx, httpReq, httpRes, errors.Wrapf(err, "failed to nav to %s", nextItemId))
 									return
 								}
 							} else {
@@ -229,83 +433,137 @@ func (w webApp) hdlr() func(httpRes http.ResponseWriter, httpReq *http.Request)
 			return
 		} //switch method
 
-		log.Debugf("Rendering item(%s) ...", currentItemId)
-		//render into buffer so that rendering can complete and define page data
-		//before we write the cookie and session and then the page content
-		//(wrong order does not save correctly)
-		var pageBuffer *bytes.Buffer
-		var pageSessionData *app.PageData
-		for {
-			pageBuffer = bytes.NewBuffer(nil)
-			var err error
-			var redirectToItemId string
-			redirectToItemId, pageSessionData, err = currentItem.Render(ctx, pageBuffer)
-			if err != nil {
-				log.Errorf("Rendering failed: %+v", err)
-				redirect(httpRes, "Failed to render. Sorry!", "Restart", "/")
-				return
-			}
-			if redirectToItemId != "" {
-				log.Debugf("Redirect to item(%s)", redirectToItemId)
-				currentItemId, currentItem, err = w.navigateTo(ctx, redirectToItemId)
-				if err != nil {
-					log.Errorf("Redirect(%s) failed: %+v", redirectToItemId, err)
-					redirect(httpRes, "Failed to render. Sorry!", "Restart", "/")
-					return
-				}
-				continue //render item redirected to...
-			}
-			break
-		} //for redirect loop
-
-		//store optional page session data
-		//it may be nil, but will be accessible to app.AppItem.Process()
-		//from CtxPageData{}
-		if pageSessionData != nil {
-			session.Values["page_data"] = pageSessionData
-			log.Debugf("UPDATED PAGE DATA ========================")
-			log.Debugf("PAGE: (%T)%+v", pageSessionData, pageSessionData)
+		currentItemId, pageSessionData, page, err := w.render(ctx, currentItemId, currentItem)
+		if err != nil {
+			w.fail(ctx, httpReq, httpRes, err)
+			return
 		}
-		//update and save session data
-		session.Values["current_item"] = currentItemId
+		w.respond(ctx, httpReq, httpRes, http.StatusOK, currentItemId, pageSessionData, page)
+	} //func()
+} //webapp.hdlr()
 
-		if err := session.Save(httpReq, httpRes); err != nil {
-			panic(fmt.Sprintf("failed to save session: %+v", err))
-		} else {
-			log.Debugf("Saved Session(%d values, id:%s, name:%s):", len(session.Values), session.ID, session.Name())
-			for n, v := range session.Values {
-				log.Debugf("  Session[%s] = (%T)%+v", n, v, v)
-			}
+// renderInPlace shows the current item again instead of processing the posted form
+func (w *webApp) renderInPlace(ctx context.Context, httpReq *http.Request, httpRes http.ResponseWriter, status int, currentItemId string, currentItem app.AppItem) {
+	currentItemId, pageSessionData, page, err := w.render(ctx, currentItemId, currentItem)
+	if err != nil {
+		w.fail(ctx, httpReq, httpRes, err)
+		return
+	}
+	w.respond(ctx, httpReq, httpRes, status, currentItemId, pageSessionData, page)
+} //webApp.renderInPlace()
+
+// render the item and follow its redirects until an item rendered a page
+// it returns the id of the item that rendered the page
+func (w *webApp) render(ctx context.Context, currentItemId string, currentItem app.AppItem) (string, *app.PageData, []byte, error) {
+	log := app.LoggerFrom(ctx)
+	log.Debugf("Rendering item(%s) ...", currentItemId)
+	//render into buffer so that rendering can complete and define page data
+	//before we write the cookie and session and then the page content
+	//(wrong order does not save correctly)
+	for {
+		redirectToItemId, pageSessionData, page, err := w.renderItem(ctx, currentItemId, currentItem)
+		if err != nil {
+			return "", nil, nil, errors.Wrapf(err, "failed to render item(%s)", currentItemId)
+		}
+		if redirectToItemId == "" {
+			return currentItemId, pageSessionData, page, nil
+		}
+		log.Debugf("Redirect to item(%s)", redirectToItemId)
+		itemRedirects.Inc(currentItemId, redirectToItemId)
+		currentItemId, currentItem, err = w.navigateTo(ctx, redirectToItemId)
+		if err != nil {
+			return "", nil, nil, errors.Wrapf(err, "redirect(%s) failed", redirectToItemId)
 		}
+	} //for redirect loop
+} //webApp.render()
+
+// renderItem renders the item once with a new page token in the context for its forms
+// the page data is never nil when a page was rendered, so that it always replaces
+// the page data of the previous page
+func (w *webApp) renderItem(ctx context.Context, itemId string, item app.AppItem) (string, *app.PageData, []byte, error) {
+	pageToken := uuid.New().String()
+	buffer := bytes.NewBuffer(nil)
+	redirectToItemId, pageSessionData, err := item.Render(context.WithValue(ctx, app.CtxPageToken{}, pageToken), buffer)
+	itemRenders.Inc(itemId, result(err))
+	if err != nil || redirectToItemId != "" {
+		return redirectToItemId, nil, nil, err
+	}
+	if pageSessionData == nil {
+		pageSessionData = &app.PageData{}
+	}
+	pageSessionData.Token = pageToken
+	pageSessionData.Item = itemId
+	return "", pageSessionData, buffer.Bytes(), nil
+} //webApp.renderItem()
+
+// respond saves the session on the rendered item and writes the page
+func (w *webApp) respond(ctx context.Context, httpReq *http.Request, httpRes http.ResponseWriter, status int, currentItemId string, pageSessionData *app.PageData, page []byte) {
+	session := ctx.Value(app.CtxSession{}).(*sessions.Session)
+
+	//store page session data to check and pass to app.AppItem.Process()
+	//in CtxPageData{} when the page posts a form
+	if pageSessionData != nil {
+		session.Values["page_data"] = pageSessionData
+	}
+	//update and save session data
+	session.Values["current_item"] = currentItemId
 
-		//encode updated cookie value into the response
-		//(written to httpRes before content)
-		clientData := ctx.Value(CtxClientData{}).(ClientData)
-		if encoded, err := w.cookieCutter.Encode(w.cookieName, clientData); err == nil {
-			cookie := &http.Cookie{
-				Name:     w.cookieName,
-				Value:    encoded,
-				Path:     "/",
-				Secure:   true,
-				HttpOnly: true,
-			}
-			http.SetCookie(httpRes, cookie)
-			log.Debugf("defined cookie(%s): (%T)%+v", w.cookieName, clientData, clientData)
-		} else {
-			log.Errorf("failed to encode cookie")
+	if err := session.Save(httpReq, httpRes); err != nil {
+		//when this was the error item, current_item is "error", so fail() does not render it again
+		w.fail(ctx, httpReq, httpRes, errors.Wrapf(err, "failed to save session on item %s", currentItemId))
+		return
+	}
+	logSession(ctx, "saved")
+
+	//remember the last item in the cookie to resume there after the session expired
+	//error pages are not remembered
+	clientData := ctx.Value(CtxClientData{}).(ClientData)
+	if status == http.StatusOK {
+		clientData.LastItem = currentItemId
+	}
+	w.setCookie(ctx, httpRes, clientData)
+
+	//write the page to the HTTP server responses
+	httpRes.Header().Set("Content-Type", "text/html")
+	httpRes.WriteHeader(status)
+	httpRes.Write(page)
+} //webApp.respond()
+
+// setCookie encodes the client data into the response cookie
+// (written to httpRes before content)
+func (w *webApp) setCookie(ctx context.Context, httpRes http.ResponseWriter, clientData ClientData) {
+	log := app.LoggerFrom(ctx)
+	if encoded, err := w.cookieCutter.Encode(w.config.CookieName, clientData); err == nil {
+		cookie := &http.Cookie{
+			Name:     w.config.CookieName,
+			Value:    encoded,
+			Path:     "/",
+			Domain:   w.config.CookieDomain,
+			Secure:   w.config.CookieSecure,
+			HttpOnly: true,
 		}
+		http.SetCookie(httpRes, cookie)
+		log.Debugf("defined cookie(%s): (%T)%+v", w.config.CookieName, clientData, clientData)
+	} else {
+		log.Errorf("failed to encode cookie")
+	}
+} //webApp.setCookie()
 
-		//write the page to the HTTP server responses
-		httpRes.Header().Set("Content-Type", "text/html")
-		httpRes.Write(pageBuffer.Bytes())
-	} //func()
-} //webapp.hdlr()
+func (w *webApp) userContext(httpReq *http.Request) context.Context {
+	//use the request id from a proxy if present
+	requestID := httpReq.Header.Get("X-Request-Id")
+	if requestID == "" {
+		requestID = uuid.New().String()
+	}
+	//the CSRF token is a secret of the session, so it is never logged
+	log := app.NewRequestLogger(log, append([]string{"csrf_token"}, w.config.LogRedact...))
+	log.Set("request", requestID)
 
-func (w webApp) userContext(httpReq *http.Request) context.Context {
 	//look at client cookie to see if returning device or a new device
 	clientData := ClientData{}
-	if cookie, err := httpReq.Cookie(w.cookieName); err == nil {
-		if err = w.cookieCutter.Decode(w.cookieName, cookie.Value, &clientData); err == nil {
+	cookie, err := httpReq.Cookie(w.config.CookieName)
+	if err == nil {
+		if err = w.cookieCutter.Decode(w.config.CookieName, cookie.Value, &clientData); err == nil {
 			//log.Debugf("Decoded cookie(%s): (%T)%+v", w.cookieName, clientData, clientData)
 		} else {
 			log.Errorf("Failed to decode cookie: %+v", err)
@@ -320,16 +578,10 @@ func (w webApp) userContext(httpReq *http.Request) context.Context {
 		clientData.DeviceID = uuid.New().String()
 		log.Debugf("New Session: %s", clientData.DeviceID)
 	}
+	log.Set("device", clientData.DeviceID)
 
-	session, err := w.sessionStore.Get(httpReq, clientData.DeviceID)
-	if err != nil {
-		log.Errorf("failed to get session data: %+v", err)
-	} else {
-		log.Debugf("Loaded Session(%s) (%d values, id:%s, name:%s):", clientData.DeviceID, len(session.Values), session.ID, session.Name())
-		for n, v := range session.Values {
-			log.Debugf("  Session[%s] = (%T)%+v", n, v, v)
-		}
-	}
+	session, sessionState := w.loadSession(log, httpReq, clientData, cookie != nil)
+	log.Set("session", sessionState)
 
 	lang, ok := session.Values["lang"].(string)
 	if !ok || len(lang) != 2 {
@@ -338,36 +590,46 @@ func (w webApp) userContext(httpReq *http.Request) context.Context {
 
 	ctx := context.Background()
 	ctx = context.WithValue(ctx, CtxClientData{}, clientData)
+	ctx = context.WithValue(ctx, app.CtxApp{}, w.app)
 	ctx = context.WithValue(ctx, app.CtxSession{}, session)
+	ctx = context.WithValue(ctx, CtxSessionState{}, sessionState)
+	if user, ok := session.Values["user"].(app.User); ok {
+		ctx = context.WithValue(ctx, app.CtxUser{}, &user)
+		log.Set("user", user.ID)
+	}
+	if w.config.CSRFCheck {
+		if token, err := sessionCSRFToken(session); err != nil {
+			log.Errorf("failed to create CSRF token: %+v", err)
+		} else {
+			ctx = context.WithValue(ctx, app.CtxCSRFToken{}, token)
+		}
+	}
 	ctx = context.WithValue(ctx, app.CtxLang{}, lang)
+	ctx = context.WithValue(ctx, app.CtxLoginEnabled{}, w.config.Auth != "none")
+	ctx = context.WithValue(ctx, app.CtxStylesheets{}, w.resources.stylesheets(w.config))
+	ctx = context.WithValue(ctx, app.CtxLogger{}, log)
+	logSession(ctx, "loaded")
 	return ctx
 } //webapp.userContext()
 
-func redirect(httpRes http.ResponseWriter, message, button, link string) {
-	//todo: template page
-	httpRes.Write([]byte(fmt.Sprintf(
-		"<form action=\"%s\" method=\"GET\">"+
-			"<p>%s</p>"+
-			"<button type=\"submit\">%s</button>"+
-			"</form>",
-		link, message, button)),
-	)
-}
-
-func logSession(ctx context.Context, title string) {
-	// log.Debugf("SESSION %s", title)
-	// session := ctx.Value(app.CtxSession{}).(*sessions.Session)
-	// for n, v := range session.Values {
-	// 	log.Debugf("  Session[%s] = (%T)%+v", n, v, v)
-	// }
-}
-
-func (w webApp) navigateTo(ctx context.Context, nextItemId string) (string, app.AppItem, error) {
-	nextItem, ok := w.app.GetItem(nextItemId)
+// navigateTo enters the next item in the session's app version
+// going home starts over on the current app version
+func (w *webApp) navigateTo(ctx context.Context, nextItemId string) (string, app.AppItem, error) {
+	session := ctx.Value(app.CtxSession{}).(*sessions.Session)
+	if nextItemId == "home" {
+		session.Values["app_version"] = w.app.Version()
+	}
+	appVersion, _ := session.Values["app_version"].(string)
+	nextItem, ok := w.app.GetItem(appVersion, nextItemId)
 	if !ok || nextItem == nil {
-		return "", nil, errors.Errorf("unknown next:\"%s\"", nextItemId)
+		return "", nil, app.NewError(app.KindNotFound, "unknown next:\"%s\" in app version \"%s\"", nextItemId, appVersion)
+	}
+	app.LoggerFrom(ctx).Debugf("Nav Item -> %s", nextItemId)
+	logField(ctx, "version", appVersion)
+	logField(ctx, "item", nextItemId)
+	if err := w.checkAccess(ctx, nextItemId, nextItem); err != nil {
+		return "", nil, errors.Wrapf(err, "cannot enter item(%s)", nextItemId)
 	}
-	log.Debugf("Nav Item -> %s", nextItemId)
 
 	// if nextItemId == "home" {
 	// 	session := ctx.Value(app.CtxSession{}).(*sessions.Session)
@@ -0,0 +1,253 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-msvc/errors"
+	"github.com/google/uuid"
+	"github.com/gorilla/sessions"
+	"github.com/jansemmelink/goweb1/app"
+	"github.com/jansemmelink/goweb1/metrics"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// authentication is built into the web server rather than the app, so that
+// passwords never pass through items and session data
+// the authenticated user is kept in the session as "user" and put in the context
+// of each request as app.CtxUser{}
+
+var authEvents = metrics.NewCounter("goweb1_auth_total", "Login, register and logout by result.", "event", "result")
+
+const minPasswordLen = 8
+
+var emailRegex = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+
+// dummyHash is compared when the user does not exist, so that a login takes as long
+// for unknown users as for a wrong password and does not tell which emails are registered
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not a password"), bcrypt.DefaultCost)
+
+func (w *webApp) handleAuth() {
+	w.mux.HandleFunc("/logout", w.logout)
+	switch w.config.Auth {
+	case "password":
+		w.mux.HandleFunc("/login", w.login)
+		w.mux.HandleFunc("/register", w.register)
+	case "otp":
+		//users are registered by verifying their phone
+		w.mux.HandleFunc("/login", w.otpLogin)
+		w.mux.Handle("/register", http.RedirectHandler("/login", http.StatusSeeOther))
+	}
+}
+
+// tmplDataForAuth is the body of login.tmpl and register.tmpl
+type tmplDataForAuth struct {
+	Name     string
+	Email    string
+	Phone    string //with AUTH=otp
+	CodeSent bool   //with AUTH=otp, ask for the code sent to Phone
+	Error    string //why the last submit failed
+	Notice   string //why the user must log in, e.g. to see a page that requires auth
+
+	FormTokens app.FormTokens //only CSRF, these pages are not items
+}
+
+func (w *webApp) login(httpRes http.ResponseWriter, httpReq *http.Request) {
+	ctx := w.userContext(httpReq)
+	form := tmplDataForAuth{Notice: loginNotice(ctx)}
+	status := http.StatusOK
+	switch httpReq.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if err := w.checkCSRF(ctx, httpReq); err != nil {
+			w.fail(ctx, httpReq, httpRes, err)
+			return
+		}
+		form.Email = strings.ToLower(strings.TrimSpace(httpReq.PostForm.Get("Email")))
+		user, err := w.authenticate(form.Email, httpReq.PostForm.Get("Password"))
+		authEvents.Inc("login", result(err))
+		if err == nil {
+			w.signIn(ctx, httpReq, httpRes, user)
+			return
+		}
+		app.LoggerFrom(ctx).Errorf("login(%s) failed: %+v", form.Email, err)
+		form.Error = authErrorMessage(err)
+		status = http.StatusUnauthorized
+	default:
+		http.Error(httpRes, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.renderAuthPage(ctx, httpReq, httpRes, status, "login", form)
+} //webApp.login()
+
+func (w *webApp) register(httpRes http.ResponseWriter, httpReq *http.Request) {
+	ctx := w.userContext(httpReq)
+	form := tmplDataForAuth{}
+	status := http.StatusOK
+	switch httpReq.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if err := w.checkCSRF(ctx, httpReq); err != nil {
+			w.fail(ctx, httpReq, httpRes, err)
+			return
+		}
+		form.Name = strings.TrimSpace(httpReq.PostForm.Get("Name"))
+		form.Email = strings.ToLower(strings.TrimSpace(httpReq.PostForm.Get("Email")))
+		user, err := w.addUser(form.Name, form.Email, httpReq.PostForm.Get("Password"), httpReq.PostForm.Get("Confirm"))
+		authEvents.Inc("register", result(err))
+		if err == nil {
+			app.LoggerFrom(ctx).Infof("registered user(%s)", user.ID)
+			w.signIn(ctx, httpReq, httpRes, user)
+			return
+		}
+		app.LoggerFrom(ctx).Errorf("register(%s) failed: %+v", form.Email, err)
+		form.Error = authErrorMessage(err)
+		status = errorStatus(app.KindOf(err))
+	default:
+		http.Error(httpRes, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.renderAuthPage(ctx, httpReq, httpRes, status, "register", form)
+} //webApp.register()
+
+// logout clears the session, so the next request starts at home without a user
+// it is only posted by the form in the navbar, so another site cannot log the user out
+func (w *webApp) logout(httpRes http.ResponseWriter, httpReq *http.Request) {
+	if httpReq.Method != http.MethodPost {
+		http.Error(httpRes, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := w.userContext(httpReq)
+	if err := w.checkCSRF(ctx, httpReq); err != nil {
+		w.fail(ctx, httpReq, httpRes, err)
+		return
+	}
+	session := ctx.Value(app.CtxSession{}).(*sessions.Session)
+	for name := range session.Values {
+		delete(session.Values, name)
+	}
+	err := session.Save(httpReq, httpRes)
+	authEvents.Inc("logout", result(err))
+	if err != nil {
+		w.fail(ctx, httpReq, httpRes, errors.Wrapf(err, "failed to save session on logout"))
+		return
+	}
+	http.Redirect(httpRes, httpReq, "/", http.StatusSeeOther)
+} //webApp.logout()
+
+// authenticate returns the user if the password is correct
+func (w *webApp) authenticate(email, password string) (app.User, error) {
+	stored, ok, err := w.users.Get(email)
+	if err != nil {
+		return app.User{}, errors.Wrapf(err, "failed to get user")
+	}
+	hash := stored.PasswordHash
+	if !ok {
+		hash = dummyHash
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil || !ok {
+		return app.User{}, app.NewError(app.KindValidation, "wrong email or password")
+	}
+	return stored.User, nil
+} //webApp.authenticate()
+
+// addUser registers a new user
+func (w *webApp) addUser(name, email, password, confirm string) (app.User, error) {
+	if name == "" {
+		return app.User{}, app.NewError(app.KindValidation, "please enter your name")
+	}
+	if !emailRegex.MatchString(email) {
+		return app.User{}, app.NewError(app.KindValidation, "invalid email address")
+	}
+	if len(password) < minPasswordLen {
+		return app.User{}, app.NewError(app.KindValidation, "password must have at least %d characters", minPasswordLen)
+	}
+	if password != confirm {
+		return app.User{}, app.NewError(app.KindValidation, "passwords do not match")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return app.User{}, errors.Wrapf(err, "failed to hash password")
+	}
+	stored := StoredUser{
+		User: app.User{
+			ID:    uuid.New().String(),
+			Email: email,
+			Name:  name,
+		},
+		PasswordHash: hash,
+		Registered:   time.Now(),
+	}
+	if err := w.users.Add(stored); err != nil {
+		return app.User{}, errors.Wrapf(err, "failed to add user")
+	}
+	return stored.User, nil
+} //webApp.addUser()
+
+// signIn binds the user to the session under a new device id, so that a device id
+// known before login cannot be used to take over the session (session fixation)
+// then redirects to continue in the app, on the item that required login if any
+func (w *webApp) signIn(ctx context.Context, httpReq *http.Request, httpRes http.ResponseWriter, user app.User) {
+	session := ctx.Value(app.CtxSession{}).(*sessions.Session)
+	clientData := ctx.Value(CtxClientData{}).(ClientData)
+	oldId := session.ID
+	clientData.DeviceID = uuid.New().String()
+	session.ID = clientData.DeviceID
+	session.Values["user"] = user
+	delete(session.Values, "csrf_token") //new token with the new session id
+	if err := session.Save(httpReq, httpRes); err != nil {
+		w.fail(ctx, httpReq, httpRes, errors.Wrapf(err, "failed to save session on login"))
+		return
+	}
+	if err := w.store.Delete(oldId); err != nil {
+		app.LoggerFrom(ctx).Errorf("failed to delete session before login: %+v", err)
+	}
+	w.setCookie(ctx, httpRes, clientData)
+	app.LoggerFrom(ctx).Infof("user(%s) logged in", user.ID)
+	if _, ok := session.Values["return_item"].(string); ok {
+		http.Redirect(httpRes, httpReq, "/?next=return", http.StatusSeeOther)
+		return
+	}
+	http.Redirect(httpRes, httpReq, "/", http.StatusSeeOther)
+} //webApp.signIn()
+
+// loginNotice tells the user why to log in when sent here from an item that requires auth
+func loginNotice(ctx context.Context) string {
+	session := ctx.Value(app.CtxSession{}).(*sessions.Session)
+	if _, ok := session.Values["return_item"].(string); ok {
+		return "Please log in to continue."
+	}
+	return ""
+}
+
+// authErrorMessage is shown on the form, only validation errors tell the user what is wrong
+func authErrorMessage(err error) string {
+	if app.KindOf(err) == app.KindValidation {
+		return app.ErrorMessage(err)
+	}
+	return "Sorry, something went wrong. Please try again later."
+}
+
+// renderAuthPage saves the session, which may have a new CSRF token or OTP, and writes the page
+func (w *webApp) renderAuthPage(ctx context.Context, httpReq *http.Request, httpRes http.ResponseWriter, status int, templateName string, form tmplDataForAuth) {
+	session := ctx.Value(app.CtxSession{}).(*sessions.Session)
+	if err := session.Save(httpReq, httpRes); err != nil {
+		w.fail(ctx, httpReq, httpRes, errors.Wrapf(err, "failed to save session on %s", templateName))
+		return
+	}
+	w.setCookie(ctx, httpRes, ctx.Value(CtxClientData{}).(ClientData))
+	form.FormTokens.CSRF, _ = ctx.Value(app.CtxCSRFToken{}).(string)
+	buffer := bytes.NewBuffer(nil)
+	if err := w.app.RenderPage(buffer, templateName, app.PageTmplData(ctx, form)); err != nil {
+		app.LoggerFrom(ctx).Errorf("failed to render %s page: %+v", templateName, err)
+		http.Error(httpRes, "failed to render page", http.StatusInternalServerError)
+		return
+	}
+	httpRes.Header().Set("Content-Type", "text/html")
+	httpRes.WriteHeader(status)
+	httpRes.Write(buffer.Bytes())
+}
@@ -0,0 +1,317 @@
+package web
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-msvc/errors"
+	"github.com/go-msvc/logger"
+)
+
+// Config of the web server
+// start with DefaultConfig() or ConfigFromEnv() and change what is needed before New()
+type Config struct {
+	ListenAddr  string //LISTEN_ADDR, or ":<PORT>" from PORT
+	TLSCertFile string //TLS_CERT_FILE, serve HTTPS when set with TLSKeyFile
+	TLSKeyFile  string //TLS_KEY_FILE
+
+	ShutdownTimeout time.Duration //SHUTDOWN_TIMEOUT for in-flight requests to complete in Run() on SIGTERM
+
+	//static files served under /resources/ are taken from Resources, then from ResourcesDir
+	//(RESOURCES_DIR) and then from the defaults in the web package, e.g. styles/styles.css
+	//Stylesheets (STYLESHEETS comma separated) are linked in each page after the default,
+	//e.g. "styles/piecejob.css" in ResourcesDir
+	Resources    fs.FS
+	ResourcesDir string
+	Stylesheets  []string
+
+	CookieName   string //COOKIE_NAME of the cookie with the device id
+	CookieDomain string //COOKIE_DOMAIN, blank for the host of the request
+	CookieSecure bool   //COOKIE_SECURE, only send cookies over HTTPS (browsers allow it for localhost)
+
+	//SESSION_STORE is memory|file|sqlite|sql|redis or any other registered with RegisterSessionStore()
+	//SESSION_STORE_ADDR depends on the store: directory for file, database file for sqlite,
+	//data source name for sql (with SESSION_STORE_DRIVER) or host:port for redis
+	SessionStore           string
+	SessionStoreAddr       string
+	SessionStoreDriver     string
+	SessionMaxAge          time.Duration //SESSION_MAX_AGE, e.g. "1h"
+	SessionCleanupInterval time.Duration //SESSION_CLEANUP_INTERVAL to delete expired sessions from the store
+
+	//when a session expired, SESSION_EXPIRED_ITEM is rendered if set, else the session expired error page
+	//SESSION_RESUME=true allows "/?next=resume" to continue on the last item of the expired session
+	SessionExpiredItem string
+	SessionResume      bool
+
+	//AUTH=password serves /login and /register with users kept in USER_STORE=memory|file
+	//(or any other registered with RegisterUserStore()), USER_STORE_ADDR is the file for the file store
+	//AUTH=otp serves /login with a code sent to the user's phone with OTP_SENDER=log|file
+	//(or any other registered with RegisterOTPSender()), OTP_SENDER_ADDR is the file for the file sender
+	//there is no default sender, log writes the codes to the log and is only for development
+	//the code is valid for OTP_TTL and after OTP_MAX_ATTEMPTS wrong codes the phone is locked for OTP_LOCKOUT
+	//a new code is sent to the same phone or from the same session only after OTP_SEND_INTERVAL
+	//and at most OTP_MAX_SENDS codes are sent to a phone in OTP_LOCKOUT
+	//AUTH=none serves no login
+	Auth            string
+	UserStore       string
+	UserStoreAddr   string
+	OTPSender       string
+	OTPSenderAddr   string
+	OTPTTL          time.Duration
+	OTPMaxAttempts  int
+	OTPLockout      time.Duration
+	OTPSendInterval time.Duration
+	OTPMaxSends     int
+
+	//CSRF_CHECK=false allows POST without the session's CSRF token in the form,
+	//only for channels that do not use cookies to authenticate, e.g. headless clients
+	CSRFCheck bool
+
+	//HASH_KEY authenticates cookies and must be 32 or 64 bytes
+	//BLOCK_KEY encrypts cookies and must be 16, 24 or 32 bytes
+	//when not set, random keys are used which means sessions do not survive a restart
+	//and cannot be shared between instances
+	HashKey  []byte
+	BlockKey []byte
+
+	//LOG_LEVEL of request logs is debug|info|error (default info), sessions are only dumped in debug
+	//LOG_REDACT (comma separated) are session keys with values not to log, e.g. "NationalId"
+	LogLevel  string
+	LogRedact []string
+}
+
+// DefaultConfig is suitable for development on localhost
+func DefaultConfig() Config {
+	return Config{
+		ListenAddr:             ":3000",
+		ShutdownTimeout:        10 * time.Second,
+		CookieName:             "goweb1",
+		CookieSecure:           true,
+		SessionStore:           "sqlite",
+		SessionStoreAddr:       "./database",
+		SessionMaxAge:          time.Hour,
+		SessionCleanupInterval: 5 * time.Minute,
+		Auth:                   "password",
+		UserStore:              "memory",
+		OTPTTL:                 5 * time.Minute,
+		OTPMaxAttempts:         5,
+		OTPLockout:             15 * time.Minute,
+		OTPSendInterval:        30 * time.Second,
+		OTPMaxSends:            5,
+		CSRFCheck:              true,
+		LogLevel:               "info",
+	}
+}
+
+// ConfigFromEnv returns DefaultConfig() with values from environment variables
+// named in the Config field comments
+func ConfigFromEnv() (Config, error) {
+	config := DefaultConfig()
+	if port := os.Getenv("PORT"); port != "" {
+		config.ListenAddr = ":" + port
+	}
+	envString(&config.ListenAddr, "LISTEN_ADDR")
+	envString(&config.TLSCertFile, "TLS_CERT_FILE")
+	envString(&config.TLSKeyFile, "TLS_KEY_FILE")
+	if err := envDuration(&config.ShutdownTimeout, "SHUTDOWN_TIMEOUT"); err != nil {
+		return Config{}, err
+	}
+	envString(&config.ResourcesDir, "RESOURCES_DIR")
+	if s := os.Getenv("STYLESHEETS"); s != "" {
+		config.Stylesheets = strings.Split(s, ",")
+	}
+	envString(&config.CookieName, "COOKIE_NAME")
+	envString(&config.CookieDomain, "COOKIE_DOMAIN")
+	if err := envBool(&config.CookieSecure, "COOKIE_SECURE"); err != nil {
+		return Config{}, err
+	}
+	envString(&config.SessionStore, "SESSION_STORE")
+	envString(&config.SessionStoreAddr, "SESSION_STORE_ADDR")
+	envString(&config.SessionStoreDriver, "SESSION_STORE_DRIVER")
+	if err := envDuration(&config.SessionMaxAge, "SESSION_MAX_AGE"); err != nil {
+		return Config{}, err
+	}
+	if err := envDuration(&config.SessionCleanupInterval, "SESSION_CLEANUP_INTERVAL"); err != nil {
+		return Config{}, err
+	}
+	envString(&config.SessionExpiredItem, "SESSION_EXPIRED_ITEM")
+	envString(&config.Auth, "AUTH")
+	envString(&config.UserStore, "USER_STORE")
+	envString(&config.UserStoreAddr, "USER_STORE_ADDR")
+	envString(&config.OTPSender, "OTP_SENDER")
+	envString(&config.OTPSenderAddr, "OTP_SENDER_ADDR")
+	if err := envDuration(&config.OTPTTL, "OTP_TTL"); err != nil {
+		return Config{}, err
+	}
+	if err := envInt(&config.OTPMaxAttempts, "OTP_MAX_ATTEMPTS"); err != nil {
+		return Config{}, err
+	}
+	if err := envDuration(&config.OTPLockout, "OTP_LOCKOUT"); err != nil {
+		return Config{}, err
+	}
+	if err := envDuration(&config.OTPSendInterval, "OTP_SEND_INTERVAL"); err != nil {
+		return Config{}, err
+	}
+	if err := envInt(&config.OTPMaxSends, "OTP_MAX_SENDS"); err != nil {
+		return Config{}, err
+	}
+	if err := envBool(&config.SessionResume, "SESSION_RESUME"); err != nil {
+		return Config{}, err
+	}
+	if err := envBool(&config.CSRFCheck, "CSRF_CHECK"); err != nil {
+		return Config{}, err
+	}
+	if s := os.Getenv("HASH_KEY"); s != "" {
+		config.HashKey = []byte(s)
+	}
+	if s := os.Getenv("BLOCK_KEY"); s != "" {
+		config.BlockKey = []byte(s)
+	}
+	envString(&config.LogLevel, "LOG_LEVEL")
+	if s := os.Getenv("LOG_REDACT"); s != "" {
+		config.LogRedact = strings.Split(s, ",")
+	}
+	return config, nil
+} //ConfigFromEnv()
+
+func envString(value *string, name string) {
+	if s := os.Getenv(name); s != "" {
+		*value = s
+	}
+}
+
+func envBool(value *bool, name string) error {
+	if s := os.Getenv(name); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return errors.Errorf("invalid %s=\"%s\" (expect true|false)", name, s)
+		}
+		*value = b
+	}
+	return nil
+}
+
+func envInt(value *int, name string) error {
+	if s := os.Getenv(name); s != "" {
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return errors.Errorf("invalid %s=\"%s\" (expect integer)", name, s)
+		}
+		*value = i
+	}
+	return nil
+}
+
+func envDuration(value *time.Duration, name string) error {
+	if s := os.Getenv(name); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return errors.Errorf("invalid %s=\"%s\" (expect duration like 1h)", name, s)
+		}
+		*value = d
+	}
+	return nil
+}
+
+var logLevels = map[string]logger.Level{
+	"debug": logger.LevelDebug,
+	"info":  logger.LevelInfo,
+	"error": logger.LevelError,
+}
+
+// cookie names are tokens as defined in RFC 6265
+var cookieNameRegex = regexp.MustCompile(`^[a-zA-Z0-9!#$%&'*+.^_|~-]+$`)
+
+// Validate is called in New() to fail before the server starts
+// keys are not required, because New() generates them when not set
+func (config Config) Validate() error {
+	if config.ListenAddr == "" {
+		return errors.Errorf("missing ListenAddr")
+	}
+	if (config.TLSCertFile == "") != (config.TLSKeyFile == "") {
+		return errors.Errorf("TLSCertFile and TLSKeyFile must both be set for HTTPS, or both blank for HTTP")
+	}
+	for _, filename := range []string{config.TLSCertFile, config.TLSKeyFile} {
+		if filename != "" {
+			if _, err := os.Stat(filename); err != nil {
+				return errors.Wrapf(err, "cannot access TLS file %s", filename)
+			}
+		}
+	}
+	if config.ShutdownTimeout <= 0 {
+		return errors.Errorf("ShutdownTimeout=%v must be positive", config.ShutdownTimeout)
+	}
+	if config.ResourcesDir != "" {
+		if info, err := os.Stat(config.ResourcesDir); err != nil || !info.IsDir() {
+			return errors.Errorf("ResourcesDir %s is not a directory", config.ResourcesDir)
+		}
+	}
+	if !cookieNameRegex.MatchString(config.CookieName) {
+		return errors.Errorf("invalid CookieName \"%s\"", config.CookieName)
+	}
+	if _, ok := sessionStoreFactory(config.SessionStore); !ok {
+		return errors.Errorf("unknown SessionStore \"%s\" (expect %s)", config.SessionStore, strings.Join(sessionStoreNames(), "|"))
+	}
+	if config.SessionMaxAge < time.Second {
+		return errors.Errorf("SessionMaxAge=%v must be at least 1s", config.SessionMaxAge)
+	}
+	if config.SessionCleanupInterval < time.Second {
+		return errors.Errorf("SessionCleanupInterval=%v must be at least 1s", config.SessionCleanupInterval)
+	}
+	switch config.Auth {
+	case "none":
+	case "password":
+		if _, ok := userStoreFactory(config.UserStore); !ok {
+			return errors.Errorf("unknown UserStore \"%s\" (expect %s)", config.UserStore, strings.Join(userStoreNames(), "|"))
+		}
+	case "otp":
+		if config.OTPSender == "" {
+			return errors.Errorf("missing OTPSender (expect %s)", strings.Join(otpSenderNames(), "|"))
+		}
+		if _, ok := otpSenderFactory(config.OTPSender); !ok {
+			return errors.Errorf("unknown OTPSender \"%s\" (expect %s)", config.OTPSender, strings.Join(otpSenderNames(), "|"))
+		}
+		if config.OTPTTL < time.Second {
+			return errors.Errorf("OTPTTL=%v must be at least 1s", config.OTPTTL)
+		}
+		if config.OTPMaxAttempts < 1 {
+			return errors.Errorf("OTPMaxAttempts=%d must be at least 1", config.OTPMaxAttempts)
+		}
+		if config.OTPLockout < 0 {
+			return errors.Errorf("OTPLockout=%v cannot be negative", config.OTPLockout)
+		}
+		if config.OTPSendInterval < 0 {
+			return errors.Errorf("OTPSendInterval=%v cannot be negative", config.OTPSendInterval)
+		}
+		if config.OTPMaxSends < 1 {
+			return errors.Errorf("OTPMaxSends=%d must be at least 1", config.OTPMaxSends)
+		}
+	default:
+		return errors.Errorf("invalid Auth \"%s\" (expect none|password|otp)", config.Auth)
+	}
+	if n := len(config.HashKey); n != 0 && n != 32 && n != 64 {
+		return errors.Errorf("HashKey is %d instead of 32 or 64 bytes", n)
+	}
+	if n := len(config.BlockKey); n != 0 && n != 16 && n != 24 && n != 32 {
+		return errors.Errorf("BlockKey is %d instead of 16, 24 or 32 bytes", n)
+	}
+	if _, ok := logLevels[config.LogLevel]; !ok {
+		return errors.Errorf("invalid LogLevel \"%s\" (expect debug|info|error)", config.LogLevel)
+	}
+	return nil
+} //Config.Validate()
+
+func (config Config) String() string {
+	//do not log the keys
+	scheme := "http"
+	if config.TLSCertFile != "" {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s on %s, cookie %s, %s sessions at %s for %v, auth %s, log %s",
+		scheme, config.ListenAddr, config.CookieName, config.SessionStore, config.SessionStoreAddr, config.SessionMaxAge, config.Auth, config.LogLevel)
+}
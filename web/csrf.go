@@ -0,0 +1,48 @@
+package web
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+
+	"github.com/gorilla/sessions"
+	"github.com/jansemmelink/goweb1/app"
+)
+
+// forms post the session's CSRF token as app.CSRFFieldName, so that another site
+// cannot make the browser submit forms in the user's session
+// the token is kept in the session as "csrf_token" and put in the context as app.CtxCSRFToken{}
+// when Config.CSRFCheck is on
+
+// sessionCSRFToken returns the token of the session and creates it on first use
+func sessionCSRFToken(session *sessions.Session) (string, error) {
+	if token, ok := session.Values["csrf_token"].(string); ok && token != "" {
+		return token, nil
+	}
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(random)
+	session.Values["csrf_token"] = token
+	return token, nil
+}
+
+// checkCSRF fails with app.KindForbidden when a POST does not have the token of the session
+func (w *webApp) checkCSRF(ctx context.Context, httpReq *http.Request) error {
+	if !w.config.CSRFCheck || httpReq.Method != http.MethodPost {
+		return nil
+	}
+	token, _ := ctx.Value(app.CtxCSRFToken{}).(string)
+	httpReq.ParseForm()
+	posted := httpReq.PostForm.Get(app.CSRFFieldName)
+	if posted == "" {
+		return app.NewError(app.KindForbidden, "form posted without %s", app.CSRFFieldName)
+	}
+	if token == "" || subtle.ConstantTimeCompare([]byte(posted), []byte(token)) != 1 {
+		return app.NewError(app.KindForbidden, "form posted with wrong %s", app.CSRFFieldName)
+	}
+	return nil
+} //webApp.checkCSRF()
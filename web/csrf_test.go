@@ -0,0 +1,57 @@
+package web
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestCSRF(t *testing.T) {
+	updated := 0
+	httpServer, client := testServer(t, testFormApp, DefaultConfig(), testThingFuncs(&updated))
+	_, body := testGet(t, client, httpServer.URL+"/")
+	valid := testFormTokens(body, url.Values{"Name": {"new name"}})
+
+	tests := []struct {
+		name  string
+		path  string
+		token string
+	}{
+		{"missing", "/", ""},
+		{"wrong", "/", "not-the-token"},
+		{"login missing", "/login", ""},
+		{"logout wrong", "/logout", "not-the-token"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			form := url.Values{"Name": {"new name"}, "Email": {"test@example.com"}, "page_token": {valid.Get("page_token")}}
+			if test.token != "" {
+				form.Set("csrf_token", test.token)
+			}
+			if res, body := testPost(t, client, httpServer.URL+test.path, form); res.StatusCode != http.StatusForbidden {
+				t.Fatalf("POST -> %d instead of %d:\n%s", res.StatusCode, http.StatusForbidden, body)
+			}
+		})
+	}
+	if updated != 0 {
+		t.Fatalf("updated %d times without the token", updated)
+	}
+	if res, body := testPost(t, client, httpServer.URL+"/", valid); res.StatusCode != http.StatusSeeOther || updated != 1 {
+		t.Fatalf("POST with token -> %d (updated %d):\n%s", res.StatusCode, updated, body)
+	}
+}
+
+func TestCSRFCheckOff(t *testing.T) {
+	updated := 0
+	config := DefaultConfig()
+	config.CSRFCheck = false
+	httpServer, client := testServer(t, testFormApp, config, testThingFuncs(&updated))
+	_, body := testGet(t, client, httpServer.URL+"/")
+	form := testFormTokens(body, url.Values{"Name": {"new name"}})
+	if form.Get("csrf_token") != "" {
+		t.Fatalf("form has csrf_token with CSRFCheck=false")
+	}
+	if res, body := testPost(t, client, httpServer.URL+"/", form); res.StatusCode != http.StatusSeeOther || updated != 1 {
+		t.Fatalf("POST -> %d (updated %d):\n%s", res.StatusCode, updated, body)
+	}
+}
@@ -0,0 +1,188 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/sessions"
+	"github.com/jansemmelink/goweb1/app"
+	"github.com/jansemmelink/goweb1/metrics"
+)
+
+// ErrorCaption is shown on the error page for a kind of error
+type ErrorCaption struct {
+	Title   string
+	Message string
+	Button  string
+	Resume  string //button to continue on the last item after the session expired (see Config.SessionResume)
+}
+
+var (
+	errorCaptionsMutex sync.Mutex
+	errorCaptions      = map[app.ErrorKind]map[string]ErrorCaption{ //by lang, "" is the default
+		app.KindInternal:       {"": {Title: "Sorry", Message: "Something went wrong.", Button: "Start over"}},
+		app.KindNotFound:       {"": {Title: "Not found", Message: "What you selected is no longer available.", Button: "Start over"}},
+		app.KindValidation:     {"": {Title: "Please check your input", Message: "", Button: "Try again"}},
+		app.KindUpstream:       {"": {Title: "Not available", Message: "We could not complete your request, please try again later.", Button: "Try again"}},
+		app.KindSessionExpired: {"": {Title: "Session expired", Message: "Your session has ended.", Button: "Start a new session", Resume: "Continue where you left off"}},
+		app.KindAuthRequired:   {"": {Title: "Login required", Message: "You need to log in to see this page.", Button: "Start over"}},
+		app.KindForbidden:      {"": {Title: "Not allowed", Message: "You do not have access to this page.", Button: "Start over"}},
+	}
+)
+
+// SetErrorCaption changes or translates the error page captions of a kind of error
+// lang "" is the default when there is no caption in the session's language
+func SetErrorCaption(kind app.ErrorKind, lang string, caption ErrorCaption) {
+	errorCaptionsMutex.Lock()
+	defer errorCaptionsMutex.Unlock()
+	if _, ok := errorCaptions[kind]; !ok {
+		errorCaptions[kind] = map[string]ErrorCaption{}
+	}
+	errorCaptions[kind][lang] = caption
+}
+
+func errorCaption(kind app.ErrorKind, lang string) ErrorCaption {
+	errorCaptionsMutex.Lock()
+	defer errorCaptionsMutex.Unlock()
+	captions, ok := errorCaptions[kind]
+	if !ok {
+		captions = errorCaptions[app.KindInternal]
+	}
+	if caption, ok := captions[lang]; ok {
+		return caption
+	}
+	return captions[""]
+}
+
+// errorStatus is the HTTP status for a kind of error
+func errorStatus(kind app.ErrorKind) int {
+	switch kind {
+	case app.KindNotFound:
+		return http.StatusNotFound
+	case app.KindValidation:
+		return http.StatusBadRequest
+	case app.KindUpstream:
+		return http.StatusBadGateway
+	case app.KindSessionExpired, app.KindAuthRequired:
+		return http.StatusUnauthorized
+	case app.KindForbidden:
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// errorLink is where the error page button goes: the current item again when the session
+// was not changed and the user can retry, else start over at home
+func errorLink(kind app.ErrorKind) string {
+	switch kind {
+	case app.KindValidation, app.KindUpstream, app.KindSessionExpired:
+		return "/"
+	}
+	return "/?next=home"
+}
+
+var errorsTotal = metrics.NewCounter("goweb1_errors_total", "Error pages by kind of error.", "kind")
+
+// tmplDataForError is the body of error.tmpl
+type tmplDataForError struct {
+	Kind    string
+	Title   string
+	Message string
+	Detail  string //what to correct after a validation error
+	Button  string
+	Link    string
+	Resume  string //caption of the resume button, only when the session can be resumed
+}
+
+// fail responds with an error page for the kind of error (see app.KindOf())
+// when the app has an "error" item, that is rendered instead with ErrorKind and ErrorMessage
+// in the session data, so the app can show its own page and decide where to go next
+// the session is only saved when the error item is rendered, the session expired or login is required,
+// so after other errors the user continues from the last page
+// when login is required and possible, it redirects to the login page instead
+func (w *webApp) fail(ctx context.Context, httpReq *http.Request, httpRes http.ResponseWriter, err error) {
+	log := app.LoggerFrom(ctx)
+	kind := app.KindOf(err)
+	status := errorStatus(kind)
+	errorsTotal.Inc(kind.String())
+	log.Errorf("%s error (HTTP %d): %+v", kind, status, err)
+
+	session := ctx.Value(app.CtxSession{}).(*sessions.Session)
+	clientData := ctx.Value(CtxClientData{}).(ClientData)
+	sessionState, _ := ctx.Value(CtxSessionState{}).(SessionState)
+	if loginPath := w.loginPath(); kind == app.KindAuthRequired && loginPath != "" {
+		//keep return_item for after login
+		if err := session.Save(httpReq, httpRes); err != nil {
+			log.Errorf("failed to save session before login: %+v", err)
+		}
+		w.setCookie(ctx, httpRes, clientData)
+		http.Redirect(httpRes, httpReq, loginPath, http.StatusSeeOther)
+		return
+	}
+	if kind == app.KindSessionExpired {
+		//start over on the current app version with the next request
+		//and write the cookie in case this is a new device id
+		delete(session.Values, "current_item")
+		delete(session.Values, "app_version")
+		delete(session.Values, "page_data")
+		if err := session.Save(httpReq, httpRes); err != nil {
+			log.Errorf("failed to save expired session: %+v", err)
+		}
+		w.setCookie(ctx, httpRes, clientData)
+	} else if currentItemId, _ := session.Values["current_item"].(string); currentItemId != "error" && sessionState != SessionUnavailable {
+		if w.renderErrorItem(ctx, httpReq, httpRes, kind, status, err) {
+			return
+		}
+	}
+
+	lang, _ := ctx.Value(app.CtxLang{}).(string)
+	caption := errorCaption(kind, lang)
+	body := tmplDataForError{
+		Kind:    kind.String(),
+		Title:   caption.Title,
+		Message: caption.Message,
+		Button:  caption.Button,
+		Link:    errorLink(kind),
+	}
+	if kind == app.KindValidation {
+		body.Detail = app.ErrorMessage(err)
+	}
+	if kind == app.KindSessionExpired && w.config.SessionResume && clientData.LastItem != "" {
+		body.Resume = caption.Resume
+	}
+	buffer := bytes.NewBuffer(nil)
+	if err := w.app.RenderPage(buffer, "error", app.PageTmplData(ctx, body)); err != nil {
+		log.Errorf("failed to render error page: %+v", err)
+		http.Error(httpRes, caption.Title, status)
+		return
+	}
+	httpRes.Header().Set("Content-Type", "text/html")
+	httpRes.WriteHeader(status)
+	httpRes.Write(buffer.Bytes())
+} //webApp.fail()
+
+// renderErrorItem renders the app's "error" item if it has one and returns true when done
+func (w *webApp) renderErrorItem(ctx context.Context, httpReq *http.Request, httpRes http.ResponseWriter, kind app.ErrorKind, status int, err error) bool {
+	log := app.LoggerFrom(ctx)
+	session := ctx.Value(app.CtxSession{}).(*sessions.Session)
+	appVersion, _ := session.Values["app_version"].(string)
+	if _, ok := w.app.GetItem(appVersion, "error"); !ok {
+		return false
+	}
+	session.Values["ErrorKind"] = kind.String()
+	session.Values["ErrorMessage"] = app.ErrorMessage(err)
+	_, errorItem, navErr := w.navigateTo(ctx, "error")
+	if navErr != nil {
+		log.Errorf("failed to enter error item: %+v", navErr)
+		return false
+	}
+	redirectToItemId, pageSessionData, page, renderErr := w.renderItem(ctx, "error", errorItem)
+	if renderErr != nil || redirectToItemId != "" {
+		log.Errorf("error item cannot redirect(%s) or failed to render: %+v", redirectToItemId, renderErr)
+		return false
+	}
+	w.respond(ctx, httpReq, httpRes, status, "error", pageSessionData, page)
+	return true
+} //webApp.renderErrorItem()
@@ -0,0 +1,175 @@
+package web
+
+import (
+	"context"
+	"html"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/jansemmelink/goweb1/app"
+)
+
+type testThing struct {
+	Name string
+	Note string
+	Age  int
+}
+
+const testFormApp = `{
+	"home":{
+		"edit":{
+			"title":{"":"Thing"},
+			"get_func":"getThing",
+			"upd_func":"updThing",
+			"saved_next":[{"item":"home"}]
+		}
+	}
+}`
+
+var testFormTokenRegex = regexp.MustCompile(`name="(csrf_token|page_token)" value="([^"]*)"`)
+
+// testServer serves the app file with the funcs on a memory session store
+// its client keeps cookies and does not follow redirects
+func testServer(t *testing.T, appFile string, config Config, funcs map[string]interface{}) (*httptest.Server, *http.Client) {
+	filename := filepath.Join(t.TempDir(), "app.json")
+	if err := os.WriteFile(filename, []byte(appFile), 0644); err != nil {
+		t.Fatalf("failed to write app file: %+v", err)
+	}
+	a := app.New()
+	for name, f := range funcs {
+		if err := a.RegisterFunc(name, f); err != nil {
+			t.Fatalf("failed to register %s: %+v", name, err)
+		}
+	}
+	if err := a.Load(filename); err != nil {
+		t.Fatalf("failed to load app: %+v", err)
+	}
+	config.SessionStore = "memory"
+	config.CookieSecure = false
+	server, err := New(a, config)
+	if err != nil {
+		t.Fatalf("failed to create server: %+v", err)
+	}
+	httpServer := httptest.NewServer(server.(*webApp).mux)
+	t.Cleanup(httpServer.Close)
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{
+		Jar: jar,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return httpServer, client
+}
+
+func testGet(t *testing.T, client *http.Client, url string) (*http.Response, string) {
+	res, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("failed to get %s: %+v", url, err)
+	}
+	defer res.Body.Close()
+	b, _ := io.ReadAll(res.Body)
+	return res, string(b)
+}
+
+func testPost(t *testing.T, client *http.Client, url string, form url.Values) (*http.Response, string) {
+	res, err := client.PostForm(url, form)
+	if err != nil {
+		t.Fatalf("failed to post %s: %+v", url, err)
+	}
+	defer res.Body.Close()
+	b, _ := io.ReadAll(res.Body)
+	return res, string(b)
+}
+
+// testFormTokens sets the csrf_token and page_token of the page in the form
+func testFormTokens(body string, form url.Values) url.Values {
+	for _, match := range testFormTokenRegex.FindAllStringSubmatch(body, -1) {
+		form.Set(match[1], match[2])
+	}
+	return form
+}
+
+func testThingFuncs(updated *int) map[string]interface{} {
+	return map[string]interface{}{
+		"getThing": func(ctx context.Context) (testThing, error) {
+			return testThing{Name: "saved name", Age: 1}, nil
+		},
+		"updThing": func(ctx context.Context, thing testThing) error {
+			if thing.Name == "" {
+				return app.NewError(app.KindValidation, "name is required")
+			}
+			*updated++
+			return nil
+		},
+	}
+}
+
+// TestFormInvalidInput posts invalid input and expects the same form again
+// with the message and the values that were posted
+func TestFormInvalidInput(t *testing.T) {
+	updated := 0
+	httpServer, client := testServer(t, testFormApp, DefaultConfig(), testThingFuncs(&updated))
+	res, body := testGet(t, client, httpServer.URL+"/")
+	if res.StatusCode != http.StatusOK || !strings.Contains(body, `value="saved name"`) {
+		t.Fatalf("GET -> %d:\n%s", res.StatusCode, body)
+	}
+
+	tests := []struct {
+		name     string
+		form     url.Values
+		expected []string
+	}{
+		{
+			name:     "upd_func",
+			form:     url.Values{"Name": {""}, "Note": {"typed note"}, "Age": {"42"}},
+			expected: []string{"name is required", `value="typed note"`, `value="42"`},
+		},
+		{
+			name:     "field",
+			form:     url.Values{"Name": {"typed name"}, "Note": {""}, "Age": {"forty"}},
+			expected: []string{`invalid Age "forty"`, `value="typed name"`, `value="forty"`},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, body = testPost(t, client, httpServer.URL+"/", testFormTokens(body, test.form))
+			if res.StatusCode != http.StatusBadRequest {
+				t.Fatalf("POST -> %d instead of %d:\n%s", res.StatusCode, http.StatusBadRequest, body)
+			}
+			for _, expected := range test.expected {
+				if !strings.Contains(html.UnescapeString(body), expected) {
+					t.Errorf("form does not contain %q:\n%s", expected, body)
+				}
+			}
+		})
+	}
+	if updated != 0 {
+		t.Errorf("invalid input updated %d times", updated)
+	}
+}
+
+// TestFormReplay posts the same form twice when the next item is the same item
+func TestFormReplay(t *testing.T) {
+	updated := 0
+	httpServer, client := testServer(t, testFormApp, DefaultConfig(), testThingFuncs(&updated))
+	_, body := testGet(t, client, httpServer.URL+"/")
+	form := testFormTokens(body, url.Values{"Name": {"new name"}, "Note": {""}, "Age": {"2"}})
+	if res, body := testPost(t, client, httpServer.URL+"/", form); res.StatusCode != http.StatusSeeOther {
+		t.Fatalf("POST -> %d instead of %d:\n%s", res.StatusCode, http.StatusSeeOther, body)
+	}
+	if res, body := testPost(t, client, httpServer.URL+"/", form); res.StatusCode != http.StatusConflict {
+		t.Fatalf("POST again -> %d instead of %d:\n%s", res.StatusCode, http.StatusConflict, body)
+	}
+	if updated != 1 {
+		t.Errorf("updated %d times instead of once", updated)
+	}
+}
@@ -0,0 +1,104 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime/debug"
+)
+
+// health endpoints for load balancers and probes (e.g. App Runner health check on /healthz)
+// they do not use sessions, so probes do not create sessions
+func (w *webApp) handleHealth() {
+	w.mux.HandleFunc("/healthz", w.healthz)
+	w.mux.HandleFunc("/readyz", w.readyz)
+	w.mux.HandleFunc("/version", w.version)
+}
+
+// healthz responds when the process is up
+func (w *webApp) healthz(httpRes http.ResponseWriter, httpReq *http.Request) {
+	httpRes.Header().Set("Content-Type", "text/plain")
+	httpRes.Write([]byte("ok\n"))
+}
+
+type readyStatus struct {
+	Ready    bool     `json:"ready"`
+	Problems []string `json:"problems,omitempty"`
+}
+
+// readyz responds 200 when requests can be served, else 503 with the problems
+func (w *webApp) readyz(httpRes http.ResponseWriter, httpReq *http.Request) {
+	status := readyStatus{Ready: true}
+	if err := w.store.Ping(); err != nil {
+		log.Errorf("readyz: session store: %+v", err)
+		status.Problems = append(status.Problems, "session store not reachable")
+	}
+	if w.app.Version() == "" {
+		status.Problems = append(status.Problems, "app not loaded")
+	} else if _, ok := w.app.GetItem("", "home"); !ok {
+		status.Problems = append(status.Problems, "app has no home item")
+	}
+	httpStatus := http.StatusOK
+	if len(status.Problems) > 0 {
+		status.Ready = false
+		httpStatus = http.StatusServiceUnavailable
+	}
+	writeJSON(httpRes, httpStatus, status)
+}
+
+type versionInfo struct {
+	Build       buildInfo `json:"build"`
+	AppVersion  string    `json:"app_version"`
+	AppVersions []string  `json:"app_versions"` //including older versions still used by sessions
+	AppItems    int       `json:"app_items"`
+}
+
+type buildInfo struct {
+	GoVersion string `json:"go_version,omitempty"`
+	Path      string `json:"path,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Revision  string `json:"revision,omitempty"`
+	Time      string `json:"time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+}
+
+// version describes the running binary and app definition
+func (w *webApp) version(httpRes http.ResponseWriter, httpReq *http.Request) {
+	info := versionInfo{
+		Build:       readBuildInfo(),
+		AppVersion:  w.app.Version(),
+		AppVersions: w.app.Versions(),
+		AppItems:    len(w.app.ItemIds()),
+	}
+	writeJSON(httpRes, http.StatusOK, info)
+}
+
+func readBuildInfo() buildInfo {
+	info := buildInfo{}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info.GoVersion = bi.GoVersion
+	info.Path = bi.Main.Path
+	info.Version = bi.Main.Version
+	for _, setting := range bi.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			info.Revision = setting.Value
+		case "vcs.time":
+			info.Time = setting.Value
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+	return info
+}
+
+func writeJSON(httpRes http.ResponseWriter, httpStatus int, value interface{}) {
+	httpRes.Header().Set("Content-Type", "application/json")
+	httpRes.Header().Set("Cache-Control", "no-store")
+	httpRes.WriteHeader(httpStatus)
+	if err := json.NewEncoder(httpRes).Encode(value); err != nil {
+		log.Errorf("failed to write JSON response: %+v", err)
+	}
+}
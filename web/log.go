@@ -0,0 +1,57 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/go-msvc/logger"
+	"github.com/gorilla/sessions"
+	"github.com/jansemmelink/goweb1/app"
+)
+
+// log is the package logger for lines outside of web requests,
+// it logs info until New() sets Config.LogLevel (see setLogLevel())
+var log = logger.New()
+
+func init() {
+	log.SetLevel(logger.LevelInfo)
+}
+
+// setLogLevel of the package loggers of web and app
+func setLogLevel(level logger.Level) {
+	log.SetLevel(level)
+	app.SetLogLevel(level)
+}
+
+// logField sets a field on all following lines of the request logger in ctx
+func logField(ctx context.Context, name string, value interface{}) {
+	if log, ok := ctx.Value(app.CtxLogger{}).(*app.RequestLogger); ok {
+		log.Set(name, value)
+	}
+}
+
+// logSession dumps the session values only when the request logs debug,
+// with values of Config.LogRedact keys redacted
+func logSession(ctx context.Context, title string) {
+	log, ok := ctx.Value(app.CtxLogger{}).(*app.RequestLogger)
+	if !ok || log.Level() < logger.LevelDebug {
+		return
+	}
+	session, ok := ctx.Value(app.CtxSession{}).(*sessions.Session)
+	if !ok || session == nil {
+		return
+	}
+	names := make([]string, 0, len(session.Values))
+	values := map[string]interface{}{}
+	for n, v := range session.Values {
+		name := fmt.Sprintf("%v", n)
+		names = append(names, name)
+		values[name] = v
+	}
+	sort.Strings(names)
+	log.Debugf("session %s (%d values)", title, len(names))
+	for _, name := range names {
+		log.Debugf("  Session[%s] = (%T)%+v", name, values[name], log.Redacted(name, values[name]))
+	}
+} //logSession()
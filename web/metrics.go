@@ -0,0 +1,56 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jansemmelink/goweb1/metrics"
+)
+
+var (
+	httpRequests        = metrics.NewCounter("goweb1_http_requests_total", "HTTP requests by method and status.", "method", "status")
+	httpRequestDuration = metrics.NewHistogram("goweb1_http_request_duration_seconds", "Duration of HTTP requests by method and status.", metrics.DurationBuckets, "method", "status")
+	itemRenders         = metrics.NewCounter("goweb1_item_renders_total", "Item renders by item id and result.", "item", "result")
+	itemProcesses       = metrics.NewCounter("goweb1_item_processes_total", "Item processing of posted input by item id and result.", "item", "result")
+	itemRedirects       = metrics.NewCounter("goweb1_item_redirects_total", "Redirects followed in the render loop.", "from", "to")
+	sessionDuration     = metrics.NewHistogram("goweb1_session_duration_seconds", "Duration of session store load and save.", metrics.DurationBuckets, "op")
+	sessionSize         = metrics.NewHistogram("goweb1_session_size_bytes", "Size of encoded session data loaded and saved.", metrics.SizeBuckets, "op")
+)
+
+// result is the label value for metrics with a result
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+// handleMetrics serves the metrics in text format on /metrics
+// like the health endpoints it does not use sessions
+func (w *webApp) handleMetrics() {
+	w.mux.Handle("/metrics", metrics.Handler())
+}
+
+// statusRecorder keeps the response status for metrics
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// instrument counts requests and their duration by method and status
+func instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(httpRes http.ResponseWriter, httpReq *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: httpRes, status: http.StatusOK}
+		next.ServeHTTP(recorder, httpReq)
+		status := strconv.Itoa(recorder.status)
+		httpRequests.Inc(httpReq.Method, status)
+		httpRequestDuration.Observe(time.Since(start).Seconds(), httpReq.Method, status)
+	})
+} //instrument()
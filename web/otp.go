@@ -0,0 +1,299 @@
+package web
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/gob"
+	"fmt"
+	"math/big"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-msvc/errors"
+	"github.com/gorilla/sessions"
+	"github.com/jansemmelink/goweb1/app"
+)
+
+// with AUTH=otp users log in with a code sent to their phone (see OTPSender)
+// there is no registration: the verified phone number is the user, available
+// to the app as app.User.Phone
+
+const otpDigits = 6
+
+// phone numbers with optional country code, after removing spaces, dashes and brackets
+var phoneRegex = regexp.MustCompile(`^\+?[0-9]{9,15}$`)
+
+// otpChallenge is the code sent to a phone, kept in the session as "otp" until verified
+// only an HMAC of the code is kept, so the code cannot be recovered from the session store
+// without Config.HashKey (a plain hash of 6 digits is reversed in 10^6 tries)
+type otpChallenge struct {
+	Phone    string
+	CodeHash []byte
+	Expires  time.Time
+}
+
+func init() {
+	gob.Register(otpChallenge{})
+}
+
+func (w *webApp) otpHash(phone, code string) []byte {
+	mac := hmac.New(sha256.New, w.config.HashKey)
+	mac.Write([]byte(phone + ":" + code))
+	return mac.Sum(nil)
+}
+
+// otpLimiter counts wrong codes per phone and locks the phone for Config.OTPLockout
+// after Config.OTPMaxAttempts, so that a new session or new code does not allow more guesses
+// wrong codes are counted for OTPLockout from the first, then the count starts over
+// it also limits the codes sent to a phone, so that it cannot be flooded with messages
+// it is kept in memory, so each instance counts on its own
+type otpLimiter struct {
+	mutex        sync.Mutex
+	maxAttempts  int
+	lockout      time.Duration
+	sendInterval time.Duration
+	maxSends     int
+	phones       map[string]*otpAttempts
+	sends        map[string]*otpSends
+	pruned       time.Time //when expired phones were last removed
+}
+
+type otpAttempts struct {
+	failed      int
+	firstFailed time.Time
+	lockedUntil time.Time
+}
+
+// otpSends are counted for Config.OTPLockout from the first, like wrong codes
+type otpSends struct {
+	sent      int
+	firstSent time.Time
+	lastSent  time.Time
+}
+
+// otpPruneInterval limits how often all phones are checked to remove expired counts
+const otpPruneInterval = time.Minute
+
+func newOTPLimiter(config Config) *otpLimiter {
+	return &otpLimiter{
+		maxAttempts:  config.OTPMaxAttempts,
+		lockout:      config.OTPLockout,
+		sendInterval: config.OTPSendInterval,
+		maxSends:     config.OTPMaxSends,
+		phones:       map[string]*otpAttempts{},
+		sends:        map[string]*otpSends{},
+		pruned:       time.Now(),
+	}
+}
+
+// expired is true when the attempts no longer count
+func (l *otpLimiter) expired(attempts *otpAttempts, now time.Time) bool {
+	if !attempts.lockedUntil.IsZero() {
+		return now.After(attempts.lockedUntil)
+	}
+	return now.After(attempts.firstFailed.Add(l.lockout))
+}
+
+// sendsExpired is true when the sends no longer count
+func (l *otpLimiter) sendsExpired(sends *otpSends, now time.Time) bool {
+	return now.After(sends.firstSent.Add(l.lockout)) && now.After(sends.lastSent.Add(l.sendInterval))
+}
+
+// prune removes expired attempts and sends of all phones, at most once per otpPruneInterval
+// must be called with the mutex locked
+func (l *otpLimiter) prune(now time.Time) {
+	if now.Sub(l.pruned) < otpPruneInterval {
+		return
+	}
+	l.pruned = now
+	for phone, attempts := range l.phones {
+		if l.expired(attempts, now) {
+			delete(l.phones, phone)
+		}
+	}
+	for phone, sends := range l.sends {
+		if l.sendsExpired(sends, now) {
+			delete(l.sends, phone)
+		}
+	}
+}
+
+// send counts a code sent to the phone, or returns false when it must wait before sending again
+func (l *otpLimiter) send(phone string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := time.Now()
+	l.prune(now)
+	sends, ok := l.sends[phone]
+	if !ok || l.sendsExpired(sends, now) {
+		sends = &otpSends{firstSent: now}
+		l.sends[phone] = sends
+	}
+	if sends.sent > 0 && now.Before(sends.lastSent.Add(l.sendInterval)) {
+		return false
+	}
+	if sends.sent >= l.maxSends {
+		return false
+	}
+	sends.sent++
+	sends.lastSent = now
+	return true
+}
+
+// locked is true while the phone is locked out
+func (l *otpLimiter) locked(phone string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := time.Now()
+	l.prune(now)
+	attempts, ok := l.phones[phone]
+	if !ok {
+		return false
+	}
+	if l.expired(attempts, now) {
+		delete(l.phones, phone)
+		return false
+	}
+	return !attempts.lockedUntil.IsZero()
+}
+
+// fail counts a wrong code and returns true when the phone is now locked
+func (l *otpLimiter) fail(phone string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := time.Now()
+	l.prune(now)
+	attempts, ok := l.phones[phone]
+	if !ok || l.expired(attempts, now) {
+		attempts = &otpAttempts{firstFailed: now}
+		l.phones[phone] = attempts
+	}
+	attempts.failed++
+	if attempts.failed < l.maxAttempts {
+		return false
+	}
+	attempts.lockedUntil = now.Add(l.lockout)
+	return true
+}
+
+func (l *otpLimiter) reset(phone string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	delete(l.phones, phone)
+}
+
+// otpLogin asks for the phone number, then for the code sent to it
+func (w *webApp) otpLogin(httpRes http.ResponseWriter, httpReq *http.Request) {
+	ctx := w.userContext(httpReq)
+	session := ctx.Value(app.CtxSession{}).(*sessions.Session)
+	form := tmplDataForAuth{Notice: loginNotice(ctx)}
+	status := http.StatusOK
+	switch httpReq.Method {
+	case http.MethodGet:
+		//start over with the phone number
+		delete(session.Values, "otp")
+	case http.MethodPost:
+		if err := w.checkCSRF(ctx, httpReq); err != nil {
+			w.fail(ctx, httpReq, httpRes, err)
+			return
+		}
+		var err error
+		if httpReq.PostForm.Has("Code") {
+			var user app.User
+			user, err = w.verifyOTP(ctx, strings.TrimSpace(httpReq.PostForm.Get("Code")))
+			authEvents.Inc("otp_verify", result(err))
+			if err == nil {
+				w.signIn(ctx, httpReq, httpRes, user)
+				return
+			}
+		} else {
+			err = w.sendOTP(ctx, httpReq.PostForm.Get("Phone"))
+			authEvents.Inc("otp_send", result(err))
+		}
+		if challenge, ok := session.Values["otp"].(otpChallenge); ok {
+			form.Phone = challenge.Phone
+			form.CodeSent = true
+		} else {
+			form.Phone = httpReq.PostForm.Get("Phone")
+		}
+		if err != nil {
+			app.LoggerFrom(ctx).Errorf("otp login(%s) failed: %+v", form.Phone, err)
+			form.Error = authErrorMessage(err)
+			status = errorStatus(app.KindOf(err))
+		}
+	default:
+		http.Error(httpRes, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.renderAuthPage(ctx, httpReq, httpRes, status, "otp", form)
+} //webApp.otpLogin()
+
+// sendOTP sends a new code to the phone and keeps it in the session to verify
+func (w *webApp) sendOTP(ctx context.Context, phone string) error {
+	phone = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(phone)
+	if !phoneRegex.MatchString(phone) {
+		return app.NewError(app.KindValidation, "invalid phone number")
+	}
+	if w.otpLimits.locked(phone) {
+		return app.NewError(app.KindValidation, "too many wrong codes, please try again later")
+	}
+	//limit codes sent from the session to any phone, and to the phone from any session
+	session := ctx.Value(app.CtxSession{}).(*sessions.Session)
+	if sent, ok := session.Values["otp_sent"].(int64); ok && time.Since(time.Unix(0, sent)) < w.config.OTPSendInterval {
+		return app.NewError(app.KindValidation, "please wait before requesting another code")
+	}
+	if !w.otpLimits.send(phone) {
+		return app.NewError(app.KindValidation, "please wait before requesting another code")
+	}
+	session.Values["otp_sent"] = time.Now().UnixNano()
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return errors.Wrapf(err, "failed to generate code")
+	}
+	code := fmt.Sprintf("%0*d", otpDigits, n.Int64())
+	if err := w.otpSender.Send(ctx, phone, code); err != nil {
+		return app.WrapError(app.KindUpstream, err, "failed to send code")
+	}
+	session.Values["otp"] = otpChallenge{
+		Phone:    phone,
+		CodeHash: w.otpHash(phone, code),
+		Expires:  time.Now().Add(w.config.OTPTTL),
+	}
+	app.LoggerFrom(ctx).Infof("sent code to %s", phone)
+	return nil
+} //webApp.sendOTP()
+
+// verifyOTP returns the user of the phone when the code is correct
+func (w *webApp) verifyOTP(ctx context.Context, code string) (app.User, error) {
+	session := ctx.Value(app.CtxSession{}).(*sessions.Session)
+	challenge, ok := session.Values["otp"].(otpChallenge)
+	if !ok {
+		return app.User{}, app.NewError(app.KindValidation, "please request a code first")
+	}
+	if w.otpLimits.locked(challenge.Phone) {
+		delete(session.Values, "otp")
+		return app.User{}, app.NewError(app.KindValidation, "too many wrong codes, please try again later")
+	}
+	if time.Now().After(challenge.Expires) {
+		delete(session.Values, "otp")
+		return app.User{}, app.NewError(app.KindValidation, "the code expired, please request a new one")
+	}
+	if !hmac.Equal(w.otpHash(challenge.Phone, code), challenge.CodeHash) {
+		if w.otpLimits.fail(challenge.Phone) {
+			delete(session.Values, "otp")
+			return app.User{}, app.NewError(app.KindValidation, "too many wrong codes, please try again in %v", w.config.OTPLockout)
+		}
+		return app.User{}, app.NewError(app.KindValidation, "wrong code")
+	}
+	delete(session.Values, "otp")
+	w.otpLimits.reset(challenge.Phone)
+	return app.User{
+		ID:    challenge.Phone,
+		Phone: challenge.Phone,
+	}, nil
+} //webApp.verifyOTP()
@@ -0,0 +1,117 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-msvc/errors"
+	"github.com/jansemmelink/goweb1/app"
+)
+
+// OTPSender delivers one-time passwords for AUTH=otp, e.g. by SMS
+type OTPSender interface {
+	Send(ctx context.Context, phone string, code string) error
+	Close() error
+}
+
+// OTPSenderFactory creates an OTP sender from the config
+type OTPSenderFactory func(config Config) (OTPSender, error)
+
+var (
+	otpSendersMutex sync.Mutex
+	otpSenders      = map[string]OTPSenderFactory{}
+)
+
+func init() {
+	MustRegisterOTPSender("log", newLogOTPSender)
+	MustRegisterOTPSender("file", newFileOTPSender)
+}
+
+// RegisterOTPSender adds an OTP sender that can be selected with Config.OTPSender
+func RegisterOTPSender(name string, factory OTPSenderFactory) error {
+	if name == "" || factory == nil {
+		return errors.Errorf("OTP sender needs name and factory")
+	}
+	otpSendersMutex.Lock()
+	defer otpSendersMutex.Unlock()
+	if _, ok := otpSenders[name]; ok {
+		return errors.Errorf("OTP sender \"%s\" already registered", name)
+	}
+	otpSenders[name] = factory
+	return nil
+} //RegisterOTPSender()
+
+func MustRegisterOTPSender(name string, factory OTPSenderFactory) {
+	if err := RegisterOTPSender(name, factory); err != nil {
+		panic(err.Error())
+	}
+}
+
+func otpSenderFactory(name string) (OTPSenderFactory, bool) {
+	otpSendersMutex.Lock()
+	defer otpSendersMutex.Unlock()
+	factory, ok := otpSenders[name]
+	return factory, ok
+}
+
+func otpSenderNames() []string {
+	otpSendersMutex.Lock()
+	defer otpSendersMutex.Unlock()
+	names := make([]string, 0, len(otpSenders))
+	for name := range otpSenders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// logOTPSender writes codes to the request log, only for local development
+type logOTPSender struct{}
+
+func newLogOTPSender(config Config) (OTPSender, error) {
+	log.Errorf("OTP_SENDER=log writes codes to the log, do not use it in production")
+	return logOTPSender{}, nil
+}
+
+func (logOTPSender) Send(ctx context.Context, phone string, code string) error {
+	app.LoggerFrom(ctx).Infof("OTP for %s: %s", phone, code)
+	return nil
+}
+
+func (logOTPSender) Close() error {
+	return nil
+}
+
+// fileOTPSender appends codes to the file Config.OTPSenderAddr, e.g. for automated tests
+type fileOTPSender struct {
+	mutex sync.Mutex
+	file  *os.File
+}
+
+func newFileOTPSender(config Config) (OTPSender, error) {
+	if config.OTPSenderAddr == "" {
+		return nil, errors.Errorf("missing OTPSenderAddr with file name for file OTP sender")
+	}
+	file, err := os.OpenFile(config.OTPSenderAddr, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open OTP file")
+	}
+	return &fileOTPSender{file: file}, nil
+}
+
+func (s *fileOTPSender) Send(ctx context.Context, phone string, code string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, err := fmt.Fprintf(s.file, "%s %s %s\n", time.Now().Format(time.RFC3339), phone, code); err != nil {
+		return errors.Wrapf(err, "failed to write OTP file")
+	}
+	return nil
+}
+
+func (s *fileOTPSender) Close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,91 @@
+package web
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestOTPLimiter(t *testing.T) {
+	lockout := 50 * time.Millisecond
+	l := newOTPLimiter(Config{OTPMaxAttempts: 2, OTPLockout: lockout})
+	if l.fail("0821234567") || l.locked("0821234567") {
+		t.Fatalf("locked after 1 of 2 attempts")
+	}
+	if !l.fail("0821234567") || !l.locked("0821234567") {
+		t.Fatalf("not locked after 2 of 2 attempts")
+	}
+	time.Sleep(lockout + 10*time.Millisecond)
+	if l.locked("0821234567") {
+		t.Fatalf("still locked after lockout")
+	}
+
+	//wrong codes are only counted for the lockout duration
+	l.fail("0827654321")
+	time.Sleep(lockout + 10*time.Millisecond)
+	if l.fail("0827654321") {
+		t.Fatalf("locked by a count that expired")
+	}
+
+	//expired counts are removed
+	time.Sleep(lockout + 10*time.Millisecond)
+	l.pruned = time.Now().Add(-otpPruneInterval)
+	l.locked("0820000000")
+	if len(l.phones) != 0 {
+		t.Fatalf("expired counts not removed: %+v", l.phones)
+	}
+}
+
+func TestOTPLimiterSends(t *testing.T) {
+	lockout := 100 * time.Millisecond
+	interval := 20 * time.Millisecond
+	l := newOTPLimiter(Config{OTPLockout: lockout, OTPSendInterval: interval, OTPMaxSends: 2})
+	if !l.send("0821234567") {
+		t.Fatalf("first code not sent")
+	}
+	if l.send("0821234567") {
+		t.Fatalf("sent again before the interval")
+	}
+	if !l.send("0827654321") {
+		t.Fatalf("other phone waits for the interval")
+	}
+	time.Sleep(interval + 5*time.Millisecond)
+	if !l.send("0821234567") {
+		t.Fatalf("second code not sent after the interval")
+	}
+	time.Sleep(interval + 5*time.Millisecond)
+	if l.send("0821234567") {
+		t.Fatalf("sent more than 2 codes in the lockout")
+	}
+	time.Sleep(lockout)
+	if !l.send("0821234567") {
+		t.Fatalf("code not sent after the lockout")
+	}
+}
+
+func TestOTPHash(t *testing.T) {
+	w := &webApp{config: Config{HashKey: bytes.Repeat([]byte{1}, 32)}}
+	other := &webApp{config: Config{HashKey: bytes.Repeat([]byte{2}, 32)}}
+	hash := w.otpHash("0821234567", "123456")
+	if !bytes.Equal(hash, w.otpHash("0821234567", "123456")) {
+		t.Fatalf("same code has another hash")
+	}
+	if bytes.Equal(hash, w.otpHash("0821234567", "123457")) || bytes.Equal(hash, w.otpHash("0827654321", "123456")) {
+		t.Fatalf("other code or phone has the same hash")
+	}
+	if bytes.Equal(hash, other.otpHash("0821234567", "123456")) {
+		t.Fatalf("same hash with another key")
+	}
+}
+
+func TestOTPSenderConfig(t *testing.T) {
+	config := DefaultConfig()
+	config.Auth = "otp"
+	if err := config.Validate(); err == nil {
+		t.Fatalf("valid without OTPSender")
+	}
+	config.OTPSender = "log"
+	if err := config.Validate(); err != nil {
+		t.Fatalf("invalid with OTPSender=log: %+v", err)
+	}
+}
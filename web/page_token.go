@@ -0,0 +1,27 @@
+package web
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/sessions"
+	"github.com/jansemmelink/goweb1/app"
+)
+
+// each rendered page has a new page token (see renderItem()) in its forms and page data,
+// so that a form is only processed on the page that rendered it, not when it is posted
+// again with the back button or from an old tab after another page was rendered
+
+// postedPage returns the data of the current page when the form posted its page token,
+// ok=false when the form is of another page
+func (w *webApp) postedPage(ctx context.Context, httpReq *http.Request, currentItemId string) (pageData app.PageData, ok bool) {
+	session := ctx.Value(app.CtxSession{}).(*sessions.Session)
+	pageData, _ = session.Values["page_data"].(app.PageData)
+	httpReq.ParseForm()
+	posted := httpReq.PostForm.Get(app.PageTokenFieldName)
+	if posted == "" || pageData.Token == "" || posted != pageData.Token || pageData.Item != currentItemId {
+		app.LoggerFrom(ctx).Errorf("stale form: posted %s=\"%s\" on page(%s:%s)", app.PageTokenFieldName, posted, pageData.Item, pageData.Token)
+		return app.PageData{}, false
+	}
+	return pageData, true
+} //webApp.postedPage()
@@ -0,0 +1,45 @@
+package web
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestPageToken(t *testing.T) {
+	updated := 0
+	httpServer, client := testServer(t, testFormApp, DefaultConfig(), testThingFuncs(&updated))
+	_, oldPage := testGet(t, client, httpServer.URL+"/")
+	_, currentPage := testGet(t, client, httpServer.URL+"/") //e.g. reloaded in another tab
+
+	stale := testFormTokens(oldPage, url.Values{"Name": {"old page"}})
+	missing := testFormTokens(currentPage, url.Values{"Name": {"no token"}})
+	missing.Del("page_token")
+	wrong := testFormTokens(currentPage, url.Values{"Name": {"wrong token"}})
+	wrong.Set("page_token", "not-the-token")
+	tests := []struct {
+		name string
+		form url.Values
+	}{
+		{"stale", stale},
+		{"missing", missing},
+		{"wrong", wrong},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, body := testPost(t, client, httpServer.URL+"/", test.form)
+			if res.StatusCode != http.StatusConflict || !strings.Contains(body, `value="saved name"`) {
+				t.Fatalf("POST -> %d instead of %d with the current page:\n%s", res.StatusCode, http.StatusConflict, body)
+			}
+			currentPage = body
+		})
+	}
+	if updated != 0 {
+		t.Fatalf("updated %d times with a stale page token", updated)
+	}
+	form := testFormTokens(currentPage, url.Values{"Name": {"current page"}})
+	if res, body := testPost(t, client, httpServer.URL+"/", form); res.StatusCode != http.StatusSeeOther || updated != 1 {
+		t.Fatalf("POST current page -> %d (updated %d):\n%s", res.StatusCode, updated, body)
+	}
+}
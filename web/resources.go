@@ -0,0 +1,164 @@
+package web
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-msvc/errors"
+)
+
+// defaultResources are served under /resources/ when not found in Config.ResourcesDir/Resources
+//
+//go:embed resources
+var defaultResources embed.FS
+
+const (
+	resourcesPath     = "/resources/"
+	defaultStylesheet = "styles/styles.css"
+)
+
+// resources serves static files with an ETag of the content hash
+// URLs made with url() include the hash as ?v=<hash> so browsers may cache them forever
+type resources struct {
+	overlays []fs.FS //first has precedence, then the next, then defaultResources
+	mutex    sync.Mutex
+	hashes   map[string]resourceHash
+}
+
+type resourceHash struct {
+	overlay int //index in overlays of the file that was hashed
+	modTime time.Time
+	hash    string
+}
+
+func newResources(config Config) (*resources, error) {
+	r := &resources{hashes: map[string]resourceHash{}}
+	if config.Resources != nil {
+		r.overlays = append(r.overlays, config.Resources)
+	}
+	if config.ResourcesDir != "" {
+		r.overlays = append(r.overlays, os.DirFS(config.ResourcesDir))
+	}
+	defaults, _ := fs.Sub(defaultResources, "resources")
+	r.overlays = append(r.overlays, defaults)
+	for _, name := range config.Stylesheets {
+		if _, err := r.url(name); err != nil {
+			return nil, errors.Wrapf(err, "invalid stylesheet")
+		}
+	}
+	return r, nil
+}
+
+// stylesheets returns the URLs of the default stylesheet followed by those in config
+func (r *resources) stylesheets(config Config) []string {
+	urls := []string{}
+	for _, name := range append([]string{defaultStylesheet}, config.Stylesheets...) {
+		url, err := r.url(name)
+		if err != nil {
+			log.Errorf("stylesheet: %+v", err)
+			continue
+		}
+		urls = append(urls, url)
+	}
+	return urls
+}
+
+// find returns the index of the first overlay that has the resource
+func (r *resources) find(name string) (int, fs.FileInfo, bool) {
+	if !fs.ValidPath(name) || name == "." {
+		return -1, nil, false
+	}
+	for index, overlay := range r.overlays {
+		if info, err := fs.Stat(overlay, name); err == nil && !info.IsDir() {
+			return index, info, true
+		}
+	}
+	return -1, nil, false
+}
+
+// open returns the content of the resource
+func (r *resources) open(name string) ([]byte, time.Time, error) {
+	index, info, ok := r.find(name)
+	if !ok {
+		return nil, time.Time{}, fs.ErrNotExist
+	}
+	data, err := fs.ReadFile(r.overlays[index], name)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return data, info.ModTime(), nil
+}
+
+// hash returns the content hash of the resource from the same overlay,
+// cached until the file is modified, or for the life of the process
+// for embedded files, which have no mod time and cannot change
+func (r *resources) hash(name string) (string, error) {
+	index, info, ok := r.find(name)
+	if !ok {
+		return "", fs.ErrNotExist
+	}
+	r.mutex.Lock()
+	cached, ok := r.hashes[name]
+	r.mutex.Unlock()
+	if ok && cached.overlay == index && (info.ModTime().IsZero() || cached.modTime.Equal(info.ModTime())) {
+		return cached.hash, nil
+	}
+	data, err := fs.ReadFile(r.overlays[index], name)
+	if err != nil {
+		return "", err
+	}
+	hash := contentHash(data)
+	r.mutex.Lock()
+	r.hashes[name] = resourceHash{overlay: index, modTime: info.ModTime(), hash: hash}
+	r.mutex.Unlock()
+	return hash, nil
+}
+
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// url returns the URL of the resource with its content hash
+func (r *resources) url(name string) (string, error) {
+	hash, err := r.hash(name)
+	if err != nil {
+		return "", errors.Wrapf(err, "resource %s not found", name)
+	}
+	return resourcesPath + name + "?v=" + hash, nil
+}
+
+func (r *resources) hdlr() http.HandlerFunc {
+	return func(httpRes http.ResponseWriter, httpReq *http.Request) {
+		if httpReq.Method != http.MethodGet && httpReq.Method != http.MethodHead {
+			http.Error(httpRes, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		name := strings.TrimPrefix(path.Clean(httpReq.URL.Path), resourcesPath)
+		data, modTime, err := r.open(name)
+		if err != nil {
+			http.Error(httpRes, "resource not found", http.StatusNotFound)
+			return
+		}
+		hash := contentHash(data)
+		httpRes.Header().Set("ETag", "\""+hash+"\"")
+		if httpReq.URL.Query().Get("v") == hash {
+			//content of this URL never changes
+			httpRes.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		} else {
+			//browser must check with the ETag before using what it has
+			httpRes.Header().Set("Cache-Control", "no-cache")
+		}
+		//ServeContent sets the content type from the name and responds 304 for If-None-Match
+		http.ServeContent(httpRes, httpReq, name, modTime, bytes.NewReader(data))
+	}
+} //resources.hdlr()
@@ -0,0 +1,66 @@
+package web
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// testCountFS counts the files read from its MapFS, which has no mod times like embed.FS
+type testCountFS struct {
+	fstest.MapFS
+	read int
+}
+
+func (countFS *testCountFS) ReadFile(name string) ([]byte, error) {
+	countFS.read++
+	return countFS.MapFS.ReadFile(name)
+}
+
+func TestResourcesHash(t *testing.T) {
+	dir := t.TempDir()
+	embedded := &testCountFS{MapFS: fstest.MapFS{"app.css": {Data: []byte("embedded")}}}
+	r := &resources{overlays: []fs.FS{os.DirFS(dir), embedded}, hashes: map[string]resourceHash{}}
+
+	//files without mod time are read once
+	embeddedHash, err := r.hash("app.css")
+	if err != nil || embeddedHash != contentHash([]byte("embedded")) {
+		t.Fatalf("hash %s (%+v) of the embedded file", embeddedHash, err)
+	}
+	for i := 0; i < 3; i++ {
+		r.hash("app.css")
+	}
+	if embedded.read != 1 {
+		t.Fatalf("embedded file read %d times", embedded.read)
+	}
+
+	//files in the dir override it and are hashed again when modified
+	file := filepath.Join(dir, "app.css")
+	if err := os.WriteFile(file, []byte("dir v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if hash, _ := r.hash("app.css"); hash != contentHash([]byte("dir v1")) {
+		t.Fatalf("hash %s instead of the file in the dir", hash)
+	}
+	if err := os.WriteFile(file, []byte("dir v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Now().Add(time.Minute)
+	if err := os.Chtimes(file, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	if hash, _ := r.hash("app.css"); hash != contentHash([]byte("dir v2")) {
+		t.Fatalf("hash %s instead of the modified file", hash)
+	}
+
+	//removing it from the dir falls back to the embedded file
+	if err := os.Remove(file); err != nil {
+		t.Fatal(err)
+	}
+	if hash, _ := r.hash("app.css"); hash != embeddedHash {
+		t.Fatalf("hash %s instead of the embedded file after removing it from the dir", hash)
+	}
+}
@@ -0,0 +1,82 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-msvc/logger"
+	"github.com/gorilla/sessions"
+	"github.com/jansemmelink/goweb1/app"
+	"github.com/jansemmelink/goweb1/metrics"
+)
+
+// SessionState tells how the session of a request was found
+type SessionState string
+
+const (
+	SessionNew         SessionState = "new"         //device without a cookie
+	SessionActive      SessionState = "active"      //session continues
+	SessionExpired     SessionState = "expired"     //cookie of a session that expired, or a cookie that cannot be decoded
+	SessionCorrupt     SessionState = "corrupt"     //session data could not be decoded
+	SessionUnavailable SessionState = "unavailable" //session store failed, e.g. not reachable
+)
+
+// CtxSessionState is the SessionState in the context of a request
+type CtxSessionState struct{}
+
+var sessionStates = metrics.NewCounter("goweb1_sessions_total", "Requests by state of the session when loaded.", "state")
+
+// loadSession gets the session of the device and tells what state it was in
+// it always returns a session, even when the store failed
+func (w *webApp) loadSession(log logger.Logger, httpReq *http.Request, clientData ClientData, hadCookie bool) (*sessions.Session, SessionState) {
+	state := SessionActive
+	session, err := w.sessionStore.Get(httpReq, clientData.DeviceID)
+	if session == nil {
+		//only other stores than serverSideStore may do this
+		session = sessions.NewSession(w.sessionStore, clientData.DeviceID)
+		session.Options = &sessions.Options{Path: "/", MaxAge: int(w.config.SessionMaxAge / time.Second)}
+		session.IsNew = true
+	}
+	switch {
+	case err != nil && isSessionCorrupt(err):
+		log.Errorf("corrupt session(%s): %+v", clientData.DeviceID, err)
+		state = SessionCorrupt
+	case err != nil:
+		log.Errorf("failed to get session data: %+v", err)
+		state = SessionUnavailable
+	case session.IsNew && hadCookie:
+		state = SessionExpired
+	case session.IsNew:
+		state = SessionNew
+	}
+	sessionStates.Inc(string(state))
+	return session, state
+} //webApp.loadSession()
+
+// sessionExpired tells the user that the session ended with Config.SessionExpiredItem,
+// or with the session expired error page when not configured
+// the new session is saved, so the next request continues on it
+func (w *webApp) sessionExpired(ctx context.Context, httpReq *http.Request, httpRes http.ResponseWriter, state SessionState) {
+	err := app.NewError(app.KindSessionExpired, "session %s", state)
+	if w.config.SessionExpiredItem == "" {
+		w.fail(ctx, httpReq, httpRes, err)
+		return
+	}
+	session := ctx.Value(app.CtxSession{}).(*sessions.Session)
+	session.Values["app_version"] = w.app.Version()
+	currentItemId, currentItem, navErr := w.navigateTo(ctx, w.config.SessionExpiredItem)
+	if navErr != nil {
+		app.LoggerFrom(ctx).Errorf("failed to enter session expired item: %+v", navErr)
+		w.fail(ctx, httpReq, httpRes, err)
+		return
+	}
+	currentItemId, pageSessionData, page, renderErr := w.render(ctx, currentItemId, currentItem)
+	if renderErr != nil {
+		app.LoggerFrom(ctx).Errorf("failed to render session expired item: %+v", renderErr)
+		w.fail(ctx, httpReq, httpRes, err)
+		return
+	}
+	app.LoggerFrom(ctx).Infof("%s", err)
+	w.respond(ctx, httpReq, httpRes, errorStatus(app.KindSessionExpired), currentItemId, pageSessionData, page)
+} //webApp.sessionExpired()
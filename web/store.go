@@ -0,0 +1,177 @@
+package web
+
+import (
+	"bytes"
+	"encoding/gob"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-msvc/errors"
+	"github.com/gorilla/sessions"
+)
+
+// SessionStore keeps session data on the server, so there is no limit on the size
+// of session data as with cookie based stores, only the device id is in the cookie
+// data is the gob encoded session values
+type SessionStore interface {
+	//Load returns ok=false when the session does not exist or expired
+	Load(id string) (data []byte, ok bool, err error)
+	Save(id string, data []byte, expires time.Time) error
+	Delete(id string) error
+
+	//Cleanup deletes expired sessions and is called periodically
+	Cleanup(now time.Time) (deleted int, err error)
+
+	//Ping checks that the store can be used, e.g. for readiness probes
+	Ping() error
+	Close() error
+}
+
+// SessionStoreFactory creates a session store from the config
+type SessionStoreFactory func(config Config) (SessionStore, error)
+
+var (
+	sessionStoresMutex sync.Mutex
+	sessionStores      = map[string]SessionStoreFactory{}
+)
+
+func init() {
+	MustRegisterSessionStore("memory", newMemorySessionStore)
+	MustRegisterSessionStore("file", newFileSessionStore)
+	MustRegisterSessionStore("sqlite", newSqliteSessionStore)
+	MustRegisterSessionStore("sql", newSqlSessionStore)
+	MustRegisterSessionStore("redis", newRedisSessionStore)
+}
+
+// RegisterSessionStore adds a session store that can be selected with Config.SessionStore
+func RegisterSessionStore(name string, factory SessionStoreFactory) error {
+	if name == "" || factory == nil {
+		return errors.Errorf("session store needs name and factory")
+	}
+	sessionStoresMutex.Lock()
+	defer sessionStoresMutex.Unlock()
+	if _, ok := sessionStores[name]; ok {
+		return errors.Errorf("session store \"%s\" already registered", name)
+	}
+	sessionStores[name] = factory
+	return nil
+} //RegisterSessionStore()
+
+func MustRegisterSessionStore(name string, factory SessionStoreFactory) {
+	if err := RegisterSessionStore(name, factory); err != nil {
+		panic(err.Error())
+	}
+}
+
+func sessionStoreFactory(name string) (SessionStoreFactory, bool) {
+	sessionStoresMutex.Lock()
+	defer sessionStoresMutex.Unlock()
+	factory, ok := sessionStores[name]
+	return factory, ok
+}
+
+func sessionStoreNames() []string {
+	sessionStoresMutex.Lock()
+	defer sessionStoresMutex.Unlock()
+	names := make([]string, 0, len(sessionStores))
+	for name := range sessionStores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// errSessionCorrupt is the parent of the error from serverSideStore.New()
+// when the session data cannot be decoded, see isSessionCorrupt()
+var errSessionCorrupt = errors.Errorf("corrupt session")
+
+// isSessionCorrupt is true when errSessionCorrupt is in the chain of parents of err
+func isSessionCorrupt(err error) bool {
+	for err != nil {
+		if err == errSessionCorrupt {
+			return true
+		}
+		parentErr, hasParent := err.(errors.IError)
+		if !hasParent {
+			return false
+		}
+		err = parentErr.Parent()
+	}
+	return false
+}
+
+// serverSideStore implements sessions.Store with a SessionStore
+// the session name (device id from the app cookie) is used as session id,
+// so it does not write its own cookie
+type serverSideStore struct {
+	store   SessionStore
+	options sessions.Options
+}
+
+func (s *serverSideStore) Get(httpReq *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(httpReq).Get(s, name)
+}
+
+// New loads the session or returns a new session (IsNew=true) if not found or expired
+func (s *serverSideStore) New(httpReq *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	options := s.options
+	session.Options = &options
+	session.ID = name
+	session.IsNew = true
+	start := time.Now()
+	data, ok, err := s.store.Load(name)
+	sessionDuration.Observe(time.Since(start).Seconds(), "load")
+	if err != nil {
+		return session, errors.Wrapf(err, "failed to load session")
+	}
+	if !ok {
+		return session, nil
+	}
+	sessionSize.Observe(float64(len(data)), "load")
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&session.Values); err != nil {
+		//the new session replaces the corrupt session when saved
+		session.Values = map[interface{}]interface{}{}
+		return session, errors.Wrapf(errSessionCorrupt, "failed to decode session: %v", err)
+	}
+	session.IsNew = false
+	return session, nil
+} //serverSideStore.New()
+
+// Save stores the session until MaxAge from now, or deletes it when MaxAge < 0
+func (s *serverSideStore) Save(httpReq *http.Request, httpRes http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		return s.store.Delete(session.ID)
+	}
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(session.Values); err != nil {
+		return errors.Wrapf(err, "failed to encode session")
+	}
+	start := time.Now()
+	expires := start.Add(time.Duration(session.Options.MaxAge) * time.Second)
+	err := s.store.Save(session.ID, buffer.Bytes(), expires)
+	sessionDuration.Observe(time.Since(start).Seconds(), "save")
+	sessionSize.Observe(float64(buffer.Len()), "save")
+	return err
+} //serverSideStore.Save()
+
+// cleanupSessions deletes expired sessions until stop is closed
+func cleanupSessions(store SessionStore, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			deleted, err := store.Cleanup(now)
+			if err != nil {
+				log.Errorf("session cleanup failed: %+v", err)
+			} else if deleted > 0 {
+				log.Debugf("session cleanup deleted %d expired sessions", deleted)
+			}
+		}
+	}
+} //cleanupSessions()
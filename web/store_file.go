@@ -0,0 +1,146 @@
+package web
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-msvc/errors"
+)
+
+// fileSessionStore keeps each session in a file in the directory Config.SessionStoreAddr
+// the file starts with the expiry time (unix nano, 8 bytes) followed by the data
+type fileSessionStore struct {
+	dir string
+}
+
+const sessionFileExt = ".session"
+
+// session ids become file names, so only allow safe characters (e.g. uuid)
+var sessionIdRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+func newFileSessionStore(config Config) (SessionStore, error) {
+	if config.SessionStoreAddr == "" {
+		return nil, errors.Errorf("missing SessionStoreAddr with directory for file session store")
+	}
+	if err := os.MkdirAll(config.SessionStoreAddr, 0700); err != nil {
+		return nil, errors.Wrapf(err, "failed to create session directory %s", config.SessionStoreAddr)
+	}
+	return &fileSessionStore{dir: config.SessionStoreAddr}, nil
+}
+
+func (s *fileSessionStore) filename(id string) (string, error) {
+	if !sessionIdRegex.MatchString(id) {
+		return "", errors.Errorf("invalid session id \"%s\"", id)
+	}
+	return filepath.Join(s.dir, id+sessionFileExt), nil
+}
+
+func (s *fileSessionStore) Load(id string) ([]byte, bool, error) {
+	filename, err := s.filename(id)
+	if err != nil {
+		return nil, false, err
+	}
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, errors.Wrapf(err, "failed to read session file")
+	}
+	expires, data, err := decodeSessionFile(content)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "invalid session file %s", filename)
+	}
+	if time.Now().After(expires) {
+		return nil, false, nil
+	}
+	return data, true, nil
+}
+
+// Save writes a temp file and renames it, so a session is never partially written
+func (s *fileSessionStore) Save(id string, data []byte, expires time.Time) error {
+	filename, err := s.filename(id)
+	if err != nil {
+		return err
+	}
+	content := make([]byte, 8, 8+len(data))
+	binary.BigEndian.PutUint64(content, uint64(expires.UnixNano()))
+	content = append(content, data...)
+	tempFile, err := os.CreateTemp(s.dir, id+".*.tmp")
+	if err != nil {
+		return errors.Wrapf(err, "failed to create session file")
+	}
+	tempFilename := tempFile.Name()
+	_, err = tempFile.Write(content)
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tempFilename)
+		return errors.Wrapf(err, "failed to write session file")
+	}
+	if err := os.Rename(tempFilename, filename); err != nil {
+		os.Remove(tempFilename)
+		return errors.Wrapf(err, "failed to replace session file")
+	}
+	return nil
+}
+
+func (s *fileSessionStore) Delete(id string) error {
+	filename, err := s.filename(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to delete session file")
+	}
+	return nil
+}
+
+func (s *fileSessionStore) Cleanup(now time.Time) (int, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to read session directory")
+	}
+	deleted := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), sessionFileExt) {
+			continue
+		}
+		filename := filepath.Join(s.dir, entry.Name())
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			continue //deleted since listed
+		}
+		if expires, _, err := decodeSessionFile(content); err == nil && !now.After(expires) {
+			continue
+		}
+		//expired or invalid
+		if err := os.Remove(filename); err == nil {
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (s *fileSessionStore) Ping() error {
+	if _, err := os.Stat(s.dir); err != nil {
+		return errors.Wrapf(err, "cannot access session directory")
+	}
+	return nil
+}
+
+func (s *fileSessionStore) Close() error {
+	return nil
+}
+
+func decodeSessionFile(content []byte) (time.Time, []byte, error) {
+	if len(content) < 8 {
+		return time.Time{}, nil, errors.Errorf("too short")
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(content[:8]))), content[8:], nil
+}
@@ -0,0 +1,67 @@
+package web
+
+import (
+	"sync"
+	"time"
+)
+
+// memorySessionStore keeps sessions in memory, e.g. for tests and single instance development
+// sessions are lost on restart
+type memorySessionStore struct {
+	mutex    sync.Mutex
+	sessions map[string]memorySession
+}
+
+type memorySession struct {
+	data    []byte
+	expires time.Time
+}
+
+func newMemorySessionStore(config Config) (SessionStore, error) {
+	return &memorySessionStore{sessions: map[string]memorySession{}}, nil
+}
+
+func (s *memorySessionStore) Load(id string) ([]byte, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	session, ok := s.sessions[id]
+	if !ok || time.Now().After(session.expires) {
+		return nil, false, nil
+	}
+	return session.data, true, nil
+}
+
+func (s *memorySessionStore) Save(id string, data []byte, expires time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sessions[id] = memorySession{data: data, expires: expires}
+	return nil
+}
+
+func (s *memorySessionStore) Delete(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+func (s *memorySessionStore) Cleanup(now time.Time) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	deleted := 0
+	for id, session := range s.sessions {
+		if now.After(session.expires) {
+			delete(s.sessions, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (s *memorySessionStore) Ping() error {
+	return nil
+}
+
+func (s *memorySessionStore) Close() error {
+	return nil
+}
@@ -0,0 +1,71 @@
+package web
+
+import (
+	"time"
+
+	"github.com/go-msvc/errors"
+	"github.com/go-redis/redis"
+)
+
+// redisSessionStore keeps sessions in redis at Config.SessionStoreAddr (host:port)
+// redis deletes expired sessions, so Cleanup has nothing to do
+type redisSessionStore struct {
+	client *redis.Client
+}
+
+const redisSessionKeyPrefix = "session_"
+
+func newRedisSessionStore(config Config) (SessionStore, error) {
+	if config.SessionStoreAddr == "" {
+		return nil, errors.Errorf("missing SessionStoreAddr with redis host:port")
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr: config.SessionStoreAddr,
+	})
+	if err := client.Ping().Err(); err != nil {
+		client.Close()
+		return nil, errors.Wrapf(err, "failed to connect to redis %s", config.SessionStoreAddr)
+	}
+	return &redisSessionStore{client: client}, nil
+}
+
+func (s *redisSessionStore) Load(id string) ([]byte, bool, error) {
+	data, err := s.client.Get(redisSessionKeyPrefix + id).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, false, nil
+		}
+		return nil, false, errors.Wrapf(err, "failed to get session")
+	}
+	return data, true, nil
+}
+
+func (s *redisSessionStore) Save(id string, data []byte, expires time.Time) error {
+	ttl := time.Until(expires)
+	if ttl <= 0 {
+		return s.Delete(id)
+	}
+	if err := s.client.Set(redisSessionKeyPrefix+id, data, ttl).Err(); err != nil {
+		return errors.Wrapf(err, "failed to set session")
+	}
+	return nil
+}
+
+func (s *redisSessionStore) Delete(id string) error {
+	if err := s.client.Del(redisSessionKeyPrefix + id).Err(); err != nil {
+		return errors.Wrapf(err, "failed to delete session")
+	}
+	return nil
+}
+
+func (s *redisSessionStore) Cleanup(now time.Time) (int, error) {
+	return 0, nil
+}
+
+func (s *redisSessionStore) Ping() error {
+	return s.client.Ping().Err()
+}
+
+func (s *redisSessionStore) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,136 @@
+package web
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-msvc/errors"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqlSessionStore keeps sessions in a database/sql table
+// the data column is a BLOB without size limit in sqlite
+// (the sqlitestore package used before encoded values with securecookie which limits it to 4KB)
+type sqlSessionStore struct {
+	db          *sql.DB
+	placeholder func(n int) string
+	stmtLoad    string
+	stmtUpdate  string
+	stmtInsert  string
+	stmtDelete  string
+	stmtCleanup string
+}
+
+const sqlSessionTable = "goweb1_sessions"
+
+// newSqliteSessionStore uses Config.SessionStoreAddr as sqlite database file
+func newSqliteSessionStore(config Config) (SessionStore, error) {
+	if config.SessionStoreAddr == "" {
+		return nil, errors.Errorf("missing SessionStoreAddr with sqlite database file")
+	}
+	config.SessionStoreDriver = "sqlite3"
+	return newSqlSessionStore(config)
+}
+
+// newSqlSessionStore uses Config.SessionStoreDriver and Config.SessionStoreAddr as data source name
+// the driver must be imported by the application, e.g. _ "github.com/lib/pq"
+func newSqlSessionStore(config Config) (SessionStore, error) {
+	if config.SessionStoreDriver == "" || config.SessionStoreAddr == "" {
+		return nil, errors.Errorf("missing SessionStoreDriver and SessionStoreAddr for sql session store")
+	}
+	db, err := sql.Open(config.SessionStoreDriver, config.SessionStoreAddr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open %s database", config.SessionStoreDriver)
+	}
+	s := &sqlSessionStore{
+		db:          db,
+		placeholder: func(n int) string { return "?" },
+	}
+	switch config.SessionStoreDriver {
+	case "postgres", "pgx":
+		s.placeholder = func(n int) string { return fmt.Sprintf("$%d", n) }
+	}
+	s.stmtLoad = s.sql("SELECT data,expires FROM %s WHERE id=%s")
+	s.stmtUpdate = s.sql("UPDATE %s SET data=%s,expires=%s WHERE id=%s")
+	s.stmtInsert = s.sql("INSERT INTO %s (id,data,expires) VALUES (%s,%s,%s)")
+	s.stmtDelete = s.sql("DELETE FROM %s WHERE id=%s")
+	s.stmtCleanup = s.sql("DELETE FROM %s WHERE expires<%s")
+
+	//expires is unix seconds to be portable between databases
+	dataType := "BLOB"
+	if strings.HasPrefix(s.placeholder(1), "$") {
+		dataType = "BYTEA"
+	}
+	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS " + sqlSessionTable + " (" +
+		"id VARCHAR(64) PRIMARY KEY," +
+		"data " + dataType + " NOT NULL," +
+		"expires BIGINT NOT NULL)"); err != nil {
+		db.Close()
+		return nil, errors.Wrapf(err, "failed to create table %s", sqlSessionTable)
+	}
+	return s, nil
+} //newSqlSessionStore()
+
+// sql fills the table name and numbered placeholders into the statement
+func (s *sqlSessionStore) sql(format string) string {
+	args := []interface{}{sqlSessionTable}
+	for n := 1; n < strings.Count(format, "%s"); n++ {
+		args = append(args, s.placeholder(n))
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+func (s *sqlSessionStore) Load(id string) ([]byte, bool, error) {
+	var data []byte
+	var expires int64
+	if err := s.db.QueryRow(s.stmtLoad, id).Scan(&data, &expires); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, false, nil
+		}
+		return nil, false, errors.Wrapf(err, "failed to load session")
+	}
+	if time.Now().Unix() > expires {
+		return nil, false, nil
+	}
+	return data, true, nil
+}
+
+func (s *sqlSessionStore) Save(id string, data []byte, expires time.Time) error {
+	result, err := s.db.Exec(s.stmtUpdate, data, expires.Unix(), id)
+	if err != nil {
+		return errors.Wrapf(err, "failed to update session")
+	}
+	if n, err := result.RowsAffected(); err == nil && n > 0 {
+		return nil
+	}
+	if _, err := s.db.Exec(s.stmtInsert, id, data, expires.Unix()); err != nil {
+		return errors.Wrapf(err, "failed to insert session")
+	}
+	return nil
+}
+
+func (s *sqlSessionStore) Delete(id string) error {
+	if _, err := s.db.Exec(s.stmtDelete, id); err != nil {
+		return errors.Wrapf(err, "failed to delete session")
+	}
+	return nil
+}
+
+func (s *sqlSessionStore) Cleanup(now time.Time) (int, error) {
+	result, err := s.db.Exec(s.stmtCleanup, now.Unix())
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to delete expired sessions")
+	}
+	deleted, _ := result.RowsAffected()
+	return int(deleted), nil
+}
+
+func (s *sqlSessionStore) Ping() error {
+	return s.db.Ping()
+}
+
+func (s *sqlSessionStore) Close() error {
+	return s.db.Close()
+}
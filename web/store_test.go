@@ -0,0 +1,179 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+)
+
+// testSessionStores creates each store to test in a new temp dir
+var testSessionStores = map[string]func(t *testing.T) SessionStore{
+	"memory": func(t *testing.T) SessionStore {
+		store, err := newMemorySessionStore(Config{})
+		if err != nil {
+			t.Fatalf("failed to create memory store: %+v", err)
+		}
+		return store
+	},
+	"file": func(t *testing.T) SessionStore {
+		store, err := newFileSessionStore(Config{SessionStoreAddr: t.TempDir()})
+		if err != nil {
+			t.Fatalf("failed to create file store: %+v", err)
+		}
+		return store
+	},
+}
+
+func TestSessionStoreRoundTrip(t *testing.T) {
+	for name, newStore := range testSessionStores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			defer store.Close()
+			id := "1234-abcd"
+			if _, ok, err := store.Load(id); ok || err != nil {
+				t.Fatalf("loaded unknown session: ok=%v err=%+v", ok, err)
+			}
+			data := []byte("session data")
+			if err := store.Save(id, data, time.Now().Add(time.Hour)); err != nil {
+				t.Fatalf("failed to save: %+v", err)
+			}
+			loaded, ok, err := store.Load(id)
+			if !ok || err != nil || !bytes.Equal(loaded, data) {
+				t.Fatalf("loaded (%v,%v,%+v) instead of saved %q", string(loaded), ok, err, data)
+			}
+			data = []byte("updated")
+			if err := store.Save(id, data, time.Now().Add(time.Hour)); err != nil {
+				t.Fatalf("failed to update: %+v", err)
+			}
+			if loaded, _, _ := store.Load(id); !bytes.Equal(loaded, data) {
+				t.Fatalf("loaded %q instead of updated %q", loaded, data)
+			}
+			if err := store.Delete(id); err != nil {
+				t.Fatalf("failed to delete: %+v", err)
+			}
+			if _, ok, _ := store.Load(id); ok {
+				t.Fatalf("loaded deleted session")
+			}
+		})
+	}
+}
+
+func TestSessionStoreExpiry(t *testing.T) {
+	for name, newStore := range testSessionStores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			defer store.Close()
+			if err := store.Save("expired", []byte("old"), time.Now().Add(-time.Second)); err != nil {
+				t.Fatalf("failed to save: %+v", err)
+			}
+			if err := store.Save("active", []byte("new"), time.Now().Add(time.Hour)); err != nil {
+				t.Fatalf("failed to save: %+v", err)
+			}
+			if _, ok, err := store.Load("expired"); ok || err != nil {
+				t.Fatalf("loaded expired session: ok=%v err=%+v", ok, err)
+			}
+			if deleted, err := store.Cleanup(time.Now()); deleted != 1 || err != nil {
+				t.Fatalf("cleanup deleted %d: %+v", deleted, err)
+			}
+			if _, ok, _ := store.Load("active"); !ok {
+				t.Fatalf("cleanup deleted active session")
+			}
+		})
+	}
+}
+
+// TestSessionMaxAge saves sessions through the gorilla sessions.Store
+// with the MaxAge of the session options
+func TestSessionMaxAge(t *testing.T) {
+	for name, newStore := range testSessionStores {
+		newStore := newStore //used after the loop by the parallel test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			s := &serverSideStore{store: newStore(t), options: sessions.Options{Path: "/", MaxAge: 1}}
+			defer s.store.Close()
+			httpReq := httptest.NewRequest("GET", "/", nil)
+			httpRes := httptest.NewRecorder()
+			id := "device-1"
+
+			session, err := s.New(httpReq, id)
+			if err != nil || !session.IsNew {
+				t.Fatalf("expected new session: IsNew=%v err=%+v", session.IsNew, err)
+			}
+			session.Values["current_item"] = "home"
+			if err := s.Save(httpReq, httpRes, session); err != nil {
+				t.Fatalf("failed to save: %+v", err)
+			}
+			session, err = s.New(httpReq, id)
+			if err != nil || session.IsNew || session.Values["current_item"] != "home" {
+				t.Fatalf("loaded IsNew=%v values=%+v err=%+v", session.IsNew, session.Values, err)
+			}
+
+			time.Sleep(1100 * time.Millisecond)
+			session, err = s.New(httpReq, id)
+			if err != nil || !session.IsNew || len(session.Values) != 0 {
+				t.Fatalf("loaded session after MaxAge: IsNew=%v values=%+v err=%+v", session.IsNew, session.Values, err)
+			}
+
+			//MaxAge < 0 deletes the session
+			session.Options.MaxAge = 60
+			if err := s.Save(httpReq, httpRes, session); err != nil {
+				t.Fatalf("failed to save: %+v", err)
+			}
+			session.Options.MaxAge = -1
+			if err := s.Save(httpReq, httpRes, session); err != nil {
+				t.Fatalf("failed to delete: %+v", err)
+			}
+			if _, ok, _ := s.store.Load(id); ok {
+				t.Fatalf("session not deleted with MaxAge < 0")
+			}
+		})
+	}
+}
+
+func TestSessionCorrupt(t *testing.T) {
+	s := &serverSideStore{store: testSessionStores["memory"](t), options: sessions.Options{Path: "/", MaxAge: 60}}
+	if err := s.store.Save("device-1", []byte("not gob"), time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("failed to save: %+v", err)
+	}
+	session, err := s.New(httptest.NewRequest("GET", "/", nil), "device-1")
+	if !isSessionCorrupt(err) {
+		t.Fatalf("expected corrupt session error instead of %+v", err)
+	}
+	if session == nil || !session.IsNew || len(session.Values) != 0 {
+		t.Fatalf("expected new session to replace corrupt session: %+v", session)
+	}
+}
+
+func TestSqlStatements(t *testing.T) {
+	tests := []struct {
+		placeholder func(n int) string
+		format      string
+		expected    string
+	}{
+		{
+			placeholder: func(n int) string { return "?" },
+			format:      "INSERT INTO %s (id,data,expires) VALUES (%s,%s,%s)",
+			expected:    "INSERT INTO goweb1_sessions (id,data,expires) VALUES (?,?,?)",
+		},
+		{
+			placeholder: func(n int) string { return fmt.Sprintf("$%d", n) },
+			format:      "UPDATE %s SET data=%s,expires=%s WHERE id=%s",
+			expected:    "UPDATE goweb1_sessions SET data=$1,expires=$2 WHERE id=$3",
+		},
+		{
+			placeholder: func(n int) string { return fmt.Sprintf("$%d", n) },
+			format:      "DELETE FROM %s WHERE expires<%s",
+			expected:    "DELETE FROM goweb1_sessions WHERE expires<$1",
+		},
+	}
+	for _, test := range tests {
+		s := &sqlSessionStore{placeholder: test.placeholder}
+		if sql := s.sql(test.format); sql != test.expected {
+			t.Errorf("%q -> %q instead of %q", test.format, sql, test.expected)
+		}
+	}
+}
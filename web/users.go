@@ -0,0 +1,118 @@
+package web
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-msvc/errors"
+	"github.com/jansemmelink/goweb1/app"
+)
+
+// UserStore keeps registered users for password login
+// users are identified by email, stored in lower case
+type UserStore interface {
+	//Get returns ok=false when the user is not registered
+	Get(email string) (user StoredUser, ok bool, err error)
+	//Add fails with app.KindValidation when the email is already registered
+	//without telling the user, see errAlreadyRegistered()
+	Add(user StoredUser) error
+	Close() error
+}
+
+// errAlreadyRegistered only logs the email, the user sees a generic message,
+// so that registration does not tell others which emails have an account
+func errAlreadyRegistered(email string) error {
+	return app.WrapError(app.KindValidation, errors.Errorf("%s is already registered", email), "could not register with these details")
+}
+
+// StoredUser is the user with its bcrypt password hash
+type StoredUser struct {
+	app.User
+	PasswordHash []byte
+	Registered   time.Time
+}
+
+// UserStoreFactory creates a user store from the config
+type UserStoreFactory func(config Config) (UserStore, error)
+
+var (
+	userStoresMutex sync.Mutex
+	userStores      = map[string]UserStoreFactory{}
+)
+
+func init() {
+	MustRegisterUserStore("memory", newMemoryUserStore)
+	MustRegisterUserStore("file", newFileUserStore)
+}
+
+// RegisterUserStore adds a user store that can be selected with Config.UserStore
+func RegisterUserStore(name string, factory UserStoreFactory) error {
+	if name == "" || factory == nil {
+		return errors.Errorf("user store needs name and factory")
+	}
+	userStoresMutex.Lock()
+	defer userStoresMutex.Unlock()
+	if _, ok := userStores[name]; ok {
+		return errors.Errorf("user store \"%s\" already registered", name)
+	}
+	userStores[name] = factory
+	return nil
+} //RegisterUserStore()
+
+func MustRegisterUserStore(name string, factory UserStoreFactory) {
+	if err := RegisterUserStore(name, factory); err != nil {
+		panic(err.Error())
+	}
+}
+
+func userStoreFactory(name string) (UserStoreFactory, bool) {
+	userStoresMutex.Lock()
+	defer userStoresMutex.Unlock()
+	factory, ok := userStores[name]
+	return factory, ok
+}
+
+func userStoreNames() []string {
+	userStoresMutex.Lock()
+	defer userStoresMutex.Unlock()
+	names := make([]string, 0, len(userStores))
+	for name := range userStores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// memoryUserStore keeps users in memory, they are lost on restart
+type memoryUserStore struct {
+	mutex sync.Mutex
+	users map[string]StoredUser
+}
+
+func newMemoryUserStore(config Config) (UserStore, error) {
+	return &memoryUserStore{users: map[string]StoredUser{}}, nil
+}
+
+func (s *memoryUserStore) Get(email string) (StoredUser, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	user, ok := s.users[strings.ToLower(email)]
+	return user, ok, nil
+}
+
+func (s *memoryUserStore) Add(user StoredUser) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	email := strings.ToLower(user.Email)
+	if _, ok := s.users[email]; ok {
+		return errAlreadyRegistered(user.Email)
+	}
+	s.users[email] = user
+	return nil
+}
+
+func (s *memoryUserStore) Close() error {
+	return nil
+}
@@ -0,0 +1,93 @@
+package web
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-msvc/errors"
+)
+
+// fileUserStore keeps all users in one JSON file Config.UserStoreAddr
+// it is read once and written on each change, which is fine for development
+// and small sites, not for many instances sharing users
+type fileUserStore struct {
+	filename string
+	mutex    sync.Mutex
+	users    map[string]StoredUser
+}
+
+func newFileUserStore(config Config) (UserStore, error) {
+	if config.UserStoreAddr == "" {
+		return nil, errors.Errorf("missing UserStoreAddr with file name for file user store")
+	}
+	s := &fileUserStore{
+		filename: config.UserStoreAddr,
+		users:    map[string]StoredUser{},
+	}
+	content, err := os.ReadFile(s.filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, errors.Wrapf(err, "failed to read users file")
+	}
+	if err := json.Unmarshal(content, &s.users); err != nil {
+		return nil, errors.Wrapf(err, "invalid users file %s", s.filename)
+	}
+	return s, nil
+}
+
+func (s *fileUserStore) Get(email string) (StoredUser, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	user, ok := s.users[strings.ToLower(email)]
+	return user, ok, nil
+}
+
+func (s *fileUserStore) Add(user StoredUser) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	email := strings.ToLower(user.Email)
+	if _, ok := s.users[email]; ok {
+		return errAlreadyRegistered(user.Email)
+	}
+	s.users[email] = user
+	if err := s.write(); err != nil {
+		delete(s.users, email)
+		return err
+	}
+	return nil
+}
+
+// write a temp file and rename it, so the file is never partially written
+func (s *fileUserStore) write() error {
+	content, err := json.MarshalIndent(s.users, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "failed to encode users")
+	}
+	tempFile, err := os.CreateTemp(filepath.Dir(s.filename), filepath.Base(s.filename)+".*.tmp")
+	if err != nil {
+		return errors.Wrapf(err, "failed to create users file")
+	}
+	tempFilename := tempFile.Name()
+	_, err = tempFile.Write(content)
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tempFilename)
+		return errors.Wrapf(err, "failed to write users file")
+	}
+	if err := os.Rename(tempFilename, s.filename); err != nil {
+		os.Remove(tempFilename)
+		return errors.Wrapf(err, "failed to replace users file")
+	}
+	return nil
+}
+
+func (s *fileUserStore) Close() error {
+	return nil
+}